@@ -1,6 +1,8 @@
 package main
 
 import (
+	"encoding/json"
+	"errors"
 	"log"
 	"net/http"
 	"os"
@@ -26,21 +28,55 @@ func main() {
 
 	// WebSocket hub
 	hub := ws.NewHub()
-	go hub.Run()
 
 	// processor
 	processor := services.NewProcessor(hub)
 
+	// new clients get recent history before live data
+	hub.Snapshot = processor.Snapshot
+	go hub.Run()
+
 	// routes
 	http.HandleFunc("/ws", func(w http.ResponseWriter, r *http.Request) {
 		handleWebSocket(hub, w, r)
 	})
 
+	http.HandleFunc("/events", func(w http.ResponseWriter, r *http.Request) {
+		handleEvents(hub, w, r)
+	})
+
 	http.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusOK)
 		w.Write([]byte("OK"))
 	})
 
+	http.HandleFunc("/sources", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(processor.SourceStatuses())
+	})
+
+	http.HandleFunc("/quota", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(processor.Quotas())
+	})
+
+	http.HandleFunc("/stats", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(processor.Stats())
+	})
+
+	http.HandleFunc("/api/v1/emotions", func(w http.ResponseWriter, r *http.Request) {
+		handleEmotions(processor, w, r)
+	})
+
+	http.HandleFunc("/api/v1/emotions/", func(w http.ResponseWriter, r *http.Request) {
+		handleEmotion(processor, w, r)
+	})
+
+	http.HandleFunc("/api/v1/aggregates", func(w http.ResponseWriter, r *http.Request) {
+		handleAggregates(processor, w, r)
+	})
+
 	http.HandleFunc("/start", func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodPost {
 			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
@@ -88,15 +124,17 @@ func main() {
 		utils.LogInfo("Processor stopped via API")
 	})
 
-	// Start processor automatically if API keys are set
+	// Start processor automatically if any news source is configured
+	// (NEWSDATA_API_KEY, or RSS_FEEDS with NEWS_SOURCES=rss)
+	// Without HUGGINGFACE_API_KEY emotions come from the offline lexicon classifier
 	// Using 5 countries: USA, Costa Rica, Brazil, Bolivia, Spain
-	if os.Getenv("NEWSDATA_API_KEY") != "" && os.Getenv("HUGGINGFACE_API_KEY") != "" {
+	if services.AnyConfigured(processor.Sources) {
 		countries := []string{"us", "cr", "br", "bo", "es"} // 5 countries for free tier
 		interval := 10 * time.Minute                        // Increased interval to avoid rate limits
 		go processor.Start(interval, countries)
 		utils.LogInfo("Processor started automatically with 5 countries: USA, Costa Rica, Brazil, Bolivia, Spain")
 	} else {
-		utils.LogInfo("API keys not set, processor will not start automatically. Use /start endpoint to start manually.")
+		utils.LogInfo("No news source configured, processor will not start automatically. Use /start endpoint to start manually.")
 	}
 
 	port := os.Getenv("PORT")
@@ -106,7 +144,13 @@ func main() {
 
 	utils.LogInfo("Server starting on port %s", port)
 	utils.LogInfo("WebSocket endpoint: ws://localhost:%s/ws", port)
+	utils.LogInfo("Server-Sent Events: http://localhost:%s/events", port)
 	utils.LogInfo("Health check: http://localhost:%s/health", port)
+	utils.LogInfo("News sources: http://localhost:%s/sources", port)
+	utils.LogInfo("API quota: http://localhost:%s/quota", port)
+	utils.LogInfo("Processor stats: http://localhost:%s/stats", port)
+	utils.LogInfo("Emotion history: http://localhost:%s/api/v1/emotions", port)
+	utils.LogInfo("Emotion aggregates: http://localhost:%s/api/v1/aggregates", port)
 	utils.LogInfo("Start processor: POST http://localhost:%s/start", port)
 	utils.LogInfo("Stop processor: POST http://localhost:%s/stop", port)
 
@@ -117,6 +161,17 @@ func main() {
 }
 
 func handleWebSocket(hub *ws.Hub, w http.ResponseWriter, r *http.Request) {
+	// ?since=<seq> resumes after the last message a reconnecting client saw
+	var since uint64
+	resume := r.URL.Query().Has("since")
+	if resume {
+		var err error
+		if since, err = ws.ParseSeq(r.URL.Query().Get("since")); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
 	upgrader := websocket.Upgrader{
 		CheckOrigin: func(r *http.Request) bool {
 			return true // all origins for development
@@ -130,8 +185,123 @@ func handleWebSocket(hub *ws.Hub, w http.ResponseWriter, r *http.Request) {
 	}
 
 	client := ws.NewClient(hub, conn)
+	if resume {
+		client.Resume(since)
+	}
 	hub.Register <- client
 
 	go client.WritePump()
 	go client.ReadPump()
 }
+
+// handleEvents streams the same messages as the WebSocket as Server-Sent Events
+func handleEvents(hub *ws.Hub, w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	filter, err := ws.ParseFilter(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	client := ws.NewSSEClient(hub, filter)
+
+	// EventSource sends the last id it saw when it reconnects
+	since := r.Header.Get("Last-Event-ID")
+	if since == "" {
+		since = r.URL.Query().Get("since")
+	}
+	if since != "" {
+		seq, err := ws.ParseSeq(since)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		client.Resume(seq)
+	}
+
+	hub.Register <- client
+	client.ServeSSE(w, r)
+}
+
+// handleEmotions lists stored events matching the query parameters
+func handleEmotions(processor *services.Processor, w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if processor.Store == nil {
+		http.Error(w, "Event store is disabled", http.StatusServiceUnavailable)
+		return
+	}
+
+	query, err := services.ParseEventQuery(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	page, err := processor.Store.Query(query)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(page)
+}
+
+// handleEmotion returns a single stored event with its source article
+func handleEmotion(processor *services.Processor, w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if processor.Store == nil {
+		http.Error(w, "Event store is disabled", http.StatusServiceUnavailable)
+		return
+	}
+
+	id := strings.TrimPrefix(r.URL.Path, "/api/v1/emotions/")
+	event, err := processor.Store.Get(id)
+	if errors.Is(err, services.ErrEventNotFound) {
+		http.Error(w, "Event not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(event)
+}
+
+// handleAggregates returns per-country emotion summaries over time buckets
+func handleAggregates(processor *services.Processor, w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	query, err := services.ParseAggregateQuery(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	buckets, err := processor.Rollup.Aggregate(query)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"bucket":  query.Bucket,
+		"buckets": buckets,
+	})
+}
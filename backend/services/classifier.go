@@ -0,0 +1,188 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+)
+
+// EmotionClassifier classifies text into emotions
+type EmotionClassifier interface {
+	// Name identifies the model or backend that produced a result
+	Name() string
+
+	// Classify classifies a single text
+	Classify(text string) (EmotionResult, error)
+
+	// ClassifyBatch classifies several texts; results are in the same order as texts
+	ClassifyBatch(texts []string) ([]EmotionResult, error)
+}
+
+// EmotionResult is the outcome of classifying one text
+type EmotionResult struct {
+	Label  string             // happy, sad, angry, surprised or neutral (see mapEmotionLabel)
+	Score  float64            // score of the top model label
+	Scores map[string]float64 // full distribution over EmotionLabels
+	Model  string             // classifier that produced the result
+}
+
+// EmotionLabels is the probability vector every result carries. Labels a model
+// doesn't predict are reported as 0.
+var EmotionLabels = []string{"joy", "sadness", "anger", "fear", "surprise", "love", "neutral", "disgust"}
+
+// labelAliases maps label spellings used by other models to EmotionLabels
+var labelAliases = map[string]string{
+	"happiness": "joy",
+	"happy":     "joy",
+	"sad":       "sadness",
+	"angry":     "anger",
+	"scared":    "fear",
+	"surprised": "surprise",
+	"disgusted": "disgust",
+}
+
+// newEmotionResult picks the top label of a model's output and keeps the full distribution
+func newEmotionResult(model string, emotions HuggingFaceResponse) EmotionResult {
+	scores := make(map[string]float64, len(EmotionLabels))
+	for _, label := range EmotionLabels {
+		scores[label] = 0
+	}
+
+	bestEmotion := emotions[0]
+	for _, emotion := range emotions {
+		label := strings.ToLower(emotion.Label)
+		if alias, ok := labelAliases[label]; ok {
+			label = alias
+		}
+		if _, ok := scores[label]; ok {
+			scores[label] += emotion.Score
+		}
+		if emotion.Score > bestEmotion.Score {
+			bestEmotion = emotion
+		}
+	}
+
+	return EmotionResult{
+		Label:  mapEmotionLabel(bestEmotion.Label),
+		Score:  bestEmotion.Score,
+		Scores: scores,
+		Model:  model,
+	}
+}
+
+// classifyEach implements ClassifyBatch with one Classify call per text
+func classifyEach(classifier EmotionClassifier, texts []string) ([]EmotionResult, error) {
+	results := make([]EmotionResult, len(texts))
+	for i, text := range texts {
+		result, err := classifier.Classify(text)
+		if err != nil {
+			return nil, fmt.Errorf("text %d: %w", i, err)
+		}
+		results[i] = result
+	}
+	return results, nil
+}
+
+// BatchError is returned by ClassifyBatch when only some texts could be classified.
+// Results holds the successful ones; the entries at Failed are zero.
+type BatchError struct {
+	Results []EmotionResult
+	Failed  []int
+	Err     error
+}
+
+func (e *BatchError) Error() string {
+	return fmt.Sprintf("%d of %d texts failed: %v", len(e.Failed), len(e.Results), e.Err)
+}
+
+func (e *BatchError) Unwrap() error {
+	return e.Err
+}
+
+// fallbackClassifier uses Fallback whenever Primary fails
+type fallbackClassifier struct {
+	Primary  EmotionClassifier
+	Fallback EmotionClassifier
+}
+
+func (fc *fallbackClassifier) Name() string {
+	return fc.Primary.Name()
+}
+
+func (fc *fallbackClassifier) Classify(text string) (EmotionResult, error) {
+	result, err := fc.Primary.Classify(text)
+	if err != nil {
+		log.Printf("%s unavailable, falling back to %s: %v", fc.Primary.Name(), fc.Fallback.Name(), err)
+		return fc.Fallback.Classify(text)
+	}
+	return result, nil
+}
+
+func (fc *fallbackClassifier) ClassifyBatch(texts []string) ([]EmotionResult, error) {
+	results, err := fc.Primary.ClassifyBatch(texts)
+
+	// only redo the texts the primary couldn't classify
+	var batchErr *BatchError
+	if errors.As(err, &batchErr) {
+		log.Printf("%s failed on %d texts, falling back to %s: %v", fc.Primary.Name(), len(batchErr.Failed), fc.Fallback.Name(), batchErr.Err)
+		results = batchErr.Results
+		for _, i := range batchErr.Failed {
+			result, err := fc.Fallback.Classify(texts[i])
+			if err != nil {
+				return nil, fmt.Errorf("text %d: %w", i, err)
+			}
+			results[i] = result
+		}
+		return results, nil
+	}
+
+	if err != nil {
+		log.Printf("%s unavailable, falling back to %s: %v", fc.Primary.Name(), fc.Fallback.Name(), err)
+		return fc.Fallback.ClassifyBatch(texts)
+	}
+	return results, nil
+}
+
+// NewEmotionClassifierFromEnv creates the classifier named by EMOTION_BACKEND
+// (default huggingface). Remote backends fall back to the offline lexicon.
+func NewEmotionClassifierFromEnv() EmotionClassifier {
+	name := os.Getenv("EMOTION_BACKEND")
+	if name == "" {
+		name = "huggingface"
+	}
+
+	classifier, err := NewEmotionClassifier(name)
+	if err != nil {
+		log.Printf("%v, using offline lexicon classifier", err)
+		return NewLexiconClassifier()
+	}
+	return classifier
+}
+
+// NewEmotionClassifier creates a classifier by its configuration name:
+// huggingface, selfhosted (EMOTION_ENDPOINT) or lexicon
+func NewEmotionClassifier(name string) (EmotionClassifier, error) {
+	switch strings.ToLower(name) {
+	case "huggingface", "hf":
+		service := NewEmotionService()
+		if service.APIKey == "" {
+			return nil, fmt.Errorf("HUGGINGFACE_API_KEY not set")
+		}
+		return &fallbackClassifier{Primary: service, Fallback: NewLexiconClassifier()}, nil
+
+	case "selfhosted", "self-hosted", "tei":
+		endpoint := os.Getenv("EMOTION_ENDPOINT")
+		if endpoint == "" {
+			return nil, fmt.Errorf("EMOTION_ENDPOINT not set")
+		}
+		return &fallbackClassifier{Primary: NewSelfHostedEmotionService(endpoint), Fallback: NewLexiconClassifier()}, nil
+
+	case "lexicon", "offline":
+		return NewLexiconClassifier(), nil
+
+	default:
+		return nil, fmt.Errorf("unknown emotion backend %q", name)
+	}
+}
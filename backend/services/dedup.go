@@ -0,0 +1,147 @@
+package services
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"os"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+)
+
+// DedupStats reports how effective deduplication has been
+type DedupStats struct {
+	Hits    int64  `json:"hits"`
+	Misses  int64  `json:"misses"`
+	Entries int    `json:"entries"`
+	TTL     string `json:"ttl"`
+}
+
+// DedupStore remembers which articles were already processed.
+// An article is a duplicate if its ID, link or normalized title was seen within TTL.
+type DedupStore struct {
+	TTL time.Duration
+
+	mu     sync.Mutex
+	seen   map[string]time.Time
+	hits   int64
+	misses int64
+}
+
+// NewDedupStore creates a dedup store with the TTL from DEDUP_TTL (default 24h)
+func NewDedupStore() *DedupStore {
+	ttl := 24 * time.Hour
+	if env := os.Getenv("DEDUP_TTL"); env != "" {
+		if parsed, err := time.ParseDuration(env); err == nil && parsed > 0 {
+			ttl = parsed
+		}
+	}
+
+	return &DedupStore{
+		TTL:  ttl,
+		seen: make(map[string]time.Time),
+	}
+}
+
+// CheckAndMark reports whether the article was already seen and marks it as seen
+func (ds *DedupStore) CheckAndMark(article NewsArticle) bool {
+	keys := dedupKeys(article)
+	if len(keys) == 0 {
+		return false
+	}
+
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+
+	now := time.Now()
+	duplicate := false
+	for _, key := range keys {
+		if expires, ok := ds.seen[key]; ok && now.Before(expires) {
+			duplicate = true
+			break
+		}
+	}
+
+	for _, key := range keys {
+		ds.seen[key] = now.Add(ds.TTL)
+	}
+
+	if duplicate {
+		ds.hits++
+	} else {
+		ds.misses++
+	}
+	return duplicate
+}
+
+// Forget removes an article so it gets processed again next time (e.g. after a failure)
+func (ds *DedupStore) Forget(article NewsArticle) {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+
+	for _, key := range dedupKeys(article) {
+		delete(ds.seen, key)
+	}
+}
+
+// Prune drops expired entries
+func (ds *DedupStore) Prune() {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+
+	now := time.Now()
+	for key, expires := range ds.seen {
+		if !now.Before(expires) {
+			delete(ds.seen, key)
+		}
+	}
+}
+
+// Stats returns the hit/miss counters
+func (ds *DedupStore) Stats() DedupStats {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+
+	return DedupStats{
+		Hits:    ds.hits,
+		Misses:  ds.misses,
+		Entries: len(ds.seen),
+		TTL:     ds.TTL.String(),
+	}
+}
+
+func dedupKeys(article NewsArticle) []string {
+	var keys []string
+	if id := strings.TrimSpace(article.ArticleID); id != "" {
+		keys = append(keys, "id:"+id)
+	}
+	if link := strings.TrimSpace(article.Link); link != "" {
+		keys = append(keys, "link:"+link)
+	}
+	if title := normalizeTitle(article.Title); title != "" {
+		sum := sha1.Sum([]byte(title))
+		keys = append(keys, "title:"+hex.EncodeToString(sum[:]))
+	}
+	return keys
+}
+
+// normalizeTitle lowercases a title and drops punctuation and extra whitespace,
+// so the same headline from two outlets hashes the same
+func normalizeTitle(title string) string {
+	var b strings.Builder
+	space := false
+	for _, r := range strings.ToLower(title) {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			if space && b.Len() > 0 {
+				b.WriteByte(' ')
+			}
+			space = false
+			b.WriteRune(r)
+		default:
+			space = true
+		}
+	}
+	return b.String()
+}
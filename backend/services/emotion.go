@@ -3,11 +3,12 @@ package services
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"log"
 	"net/http"
 	"os"
-	"strings"
 	"time"
 )
 
@@ -18,10 +19,18 @@ type EmotionResponse struct {
 
 type HuggingFaceResponse []EmotionResponse
 
+// EmotionService classifies text with a Hugging Face text-classification model,
+// either through the Hugging Face router or a self-hosted inference server
 type EmotionService struct {
-	APIKey string
-	Client *http.Client
-	Model  string
+	APIKey    string
+	Client    *http.Client
+	Model     string
+	Endpoints []string // tried in order; 404/410 moves on to the next one
+
+	// KeyRequired is set for the hosted Hugging Face API, which rejects anonymous requests
+	KeyRequired bool
+
+	Retry RetryPolicy
 }
 
 func NewEmotionService() *EmotionService {
@@ -36,44 +45,206 @@ func NewEmotionService() *EmotionService {
 			Timeout: 30 * time.Second,
 		},
 		Model: model,
+		Endpoints: []string{
+			fmt.Sprintf("https://router.huggingface.co/hf-inference/v1/models/%s", model),
+			fmt.Sprintf("https://router.huggingface.co/hf-inference/models/%s", model),
+			fmt.Sprintf("https://api-inference.huggingface.co/models/%s", model), // Fallback to old endpoint
+		},
+		KeyRequired: true,
+		Retry:       NewRetryPolicy(),
 	}
 }
 
+// NewSelfHostedEmotionService creates a classifier for a self-hosted inference
+// server speaking the Hugging Face text-classification protocol (e.g. TEI)
+func NewSelfHostedEmotionService(endpoint string) *EmotionService {
+	model := os.Getenv("EMOTION_MODEL_NAME")
+	if model == "" {
+		model = "self-hosted"
+	}
+
+	return &EmotionService{
+		APIKey: os.Getenv("EMOTION_API_KEY"),
+		Client: &http.Client{
+			Timeout: 30 * time.Second,
+		},
+		Model:     model,
+		Endpoints: []string{endpoint},
+		Retry:     NewRetryPolicy(),
+	}
+}
+
+// Name implements EmotionClassifier
+func (es *EmotionService) Name() string {
+	return es.Model
+}
+
+// AnalyzeEmotion returns the mapped top label and its score
 func (es *EmotionService) AnalyzeEmotion(text string) (string, float64, error) {
-	if es.APIKey == "" {
-		return "", 0, fmt.Errorf("HUGGINGFACE_API_KEY not set in environment variables")
+	result, err := es.Classify(text)
+	if err != nil {
+		return "", 0, err
 	}
+	return result.Label, result.Score, nil
+}
 
-	endpoints := []string{
-		fmt.Sprintf("https://router.huggingface.co/hf-inference/v1/models/%s", es.Model),
-		fmt.Sprintf("https://router.huggingface.co/hf-inference/models/%s", es.Model),
-		fmt.Sprintf("https://api-inference.huggingface.co/models/%s", es.Model), // Fallback to old endpoint
+// Classify implements EmotionClassifier
+func (es *EmotionService) Classify(text string) (EmotionResult, error) {
+	if es.KeyRequired && es.APIKey == "" {
+		return EmotionResult{}, fmt.Errorf("HUGGINGFACE_API_KEY not set in environment variables")
 	}
 
 	var lastErr error
-	for _, url := range endpoints {
+	for _, url := range es.Endpoints {
 		result, err := es.tryAnalyzeWithEndpoint(url, text)
 		if err == nil {
-			return result.emotion, result.score, nil
+			return result, nil
 		}
 		lastErr = err
-		if err != nil && (strings.Contains(err.Error(), "410") || strings.Contains(err.Error(), "404")) {
+		if hasStatus(err, http.StatusNotFound, http.StatusGone) {
 			continue
 		}
+		// anything else already went through the retry policy
+		break
 	}
 
-	return "", 0, fmt.Errorf("all endpoint attempts failed, last error: %w", lastErr)
+	return EmotionResult{}, fmt.Errorf("all endpoint attempts failed, last error: %w", lastErr)
 }
 
-type emotionResult struct {
-	emotion string
-	score   float64
+// ClassifyBatch implements EmotionClassifier. All texts are sent in one request;
+// texts missing from the response, or all of them when the response can't be
+// parsed, are retried with single requests. When the request itself fails (rate
+// limit, outage, bad key) every text is reported failed without further requests.
+func (es *EmotionService) ClassifyBatch(texts []string) ([]EmotionResult, error) {
+	if len(texts) == 0 {
+		return []EmotionResult{}, nil
+	}
+	if len(texts) == 1 {
+		return classifyEach(es, texts)
+	}
+	if es.KeyRequired && es.APIKey == "" {
+		return nil, fmt.Errorf("HUGGINGFACE_API_KEY not set in environment variables")
+	}
+
+	var batch []HuggingFaceResponse
+	var lastErr error
+	for _, url := range es.Endpoints {
+		batch, lastErr = es.tryBatchWithEndpoint(url, texts)
+		if lastErr == nil {
+			break
+		}
+		if hasStatus(lastErr, http.StatusNotFound, http.StatusGone) {
+			continue
+		}
+		break
+	}
+
+	results := make([]EmotionResult, len(texts))
+	var apiErr *APIError
+	if errors.As(lastErr, &apiErr) {
+		// single requests would hit the same error, once per text
+		failed := make([]int, len(texts))
+		for i := range failed {
+			failed[i] = i
+		}
+		return results, &BatchError{Results: results, Failed: failed, Err: lastErr}
+	}
+	if lastErr != nil {
+		log.Printf("Batch response unusable, classifying %d texts one by one: %v", len(texts), lastErr)
+		batch = nil
+	}
+
+	var failed []int
+	var lastFailure error
+	for i, text := range texts {
+		if i < len(batch) && len(batch[i]) > 0 {
+			results[i] = newEmotionResult(es.Model, batch[i])
+			continue
+		}
+		if IsRetryable(lastFailure) {
+			// rate limited or down, don't send the remaining texts
+			failed = append(failed, i)
+			continue
+		}
+
+		result, err := es.Classify(text)
+		if err != nil {
+			failed = append(failed, i)
+			lastFailure = err
+			continue
+		}
+		results[i] = result
+	}
+
+	if len(failed) > 0 {
+		return results, &BatchError{Results: results, Failed: failed, Err: lastFailure}
+	}
+	return results, nil
+}
+
+// tryBatchWithEndpoint sends several inputs in one request. Each input gets
+// its own list of label scores in the response, in input order.
+func (es *EmotionService) tryBatchWithEndpoint(url string, texts []string) ([]HuggingFaceResponse, error) {
+	body, err := es.postInputs(url, texts)
+	if err != nil {
+		return nil, err
+	}
+
+	var nested []HuggingFaceResponse
+	if err := json.Unmarshal(body, &nested); err == nil && len(nested) == len(texts) {
+		return nested, nil
+	}
+
+	// some servers only return the top label per input
+	var flat HuggingFaceResponse
+	if err := json.Unmarshal(body, &flat); err == nil && len(flat) == len(texts) {
+		batch := make([]HuggingFaceResponse, len(flat))
+		for i, emotion := range flat {
+			if emotion.Label != "" {
+				batch[i] = HuggingFaceResponse{emotion}
+			}
+		}
+		return batch, nil
+	}
+
+	return nil, fmt.Errorf("could not parse batch response for %d inputs", len(texts))
 }
 
 // tryAnalyzeWithEndpoint tries to analyze emotion with a specific endpoint according to the documentation at https://huggingface.co/docs/api-inference/quicktour
-func (es *EmotionService) tryAnalyzeWithEndpoint(url, text string) (*emotionResult, error) {
+func (es *EmotionService) tryAnalyzeWithEndpoint(url, text string) (EmotionResult, error) {
+	body, err := es.postInputs(url, text)
+	if err != nil {
+		return EmotionResult{}, err
+	}
+
+	// Parse response - Handle different response formats
+	emotions, err := es.parseEmotionResponse(body)
+	if err != nil {
+		return EmotionResult{}, err
+	}
+
+	if len(emotions) == 0 {
+		return EmotionResult{}, fmt.Errorf("no emotions found in response")
+	}
+
+	return newEmotionResult(es.Model, emotions), nil
+}
+
+// postInputs posts {"inputs": inputs} to an endpoint and returns the response body,
+// retrying while the model is loading or rate limited
+func (es *EmotionService) postInputs(url string, inputs interface{}) ([]byte, error) {
+	var body []byte
+	err := es.Retry.Do(func() error {
+		var err error
+		body, err = es.postInputsOnce(url, inputs)
+		return err
+	})
+	return body, err
+}
+
+func (es *EmotionService) postInputsOnce(url string, inputs interface{}) ([]byte, error) {
 	payload := map[string]interface{}{
-		"inputs": text,
+		"inputs": inputs,
 	}
 
 	jsonData, err := json.Marshal(payload)
@@ -87,50 +258,29 @@ func (es *EmotionService) tryAnalyzeWithEndpoint(url, text string) (*emotionResu
 	}
 
 	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", es.APIKey))
+	if es.APIKey != "" {
+		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", es.APIKey))
+	}
 	req.Header.Set("x-use-cache", "false")
 
 	// Make request
 	resp, err := es.Client.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("failed to make request: %w", err)
+		return nil, networkError("emotion", err)
 	}
 	defer resp.Body.Close()
 
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read response: %w", err)
+		return nil, networkError("emotion", err)
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		// it's a 503 (model loading)
-		if resp.StatusCode == http.StatusServiceUnavailable {
-			time.Sleep(5 * time.Second)
-			return nil, fmt.Errorf("model is loading (503)")
-		}
-		return nil, fmt.Errorf("API returned status %d: %s", resp.StatusCode, string(body))
-	}
-
-	// Parse response - Handle different response formats
-	emotions, err := es.parseEmotionResponse(body)
-	if err != nil {
-		return nil, err
-	}
-
-	if len(emotions) == 0 {
-		return nil, fmt.Errorf("no emotions found in response")
+		// a 503 usually means the model is loading, newAPIError picks up estimated_time
+		return nil, newAPIError("emotion", resp, body)
 	}
 
-	bestEmotion := emotions[0]
-	for _, emotion := range emotions {
-		if emotion.Score > bestEmotion.Score {
-			bestEmotion = emotion
-		}
-	}
-
-	emotionLabel := mapEmotionLabel(bestEmotion.Label)
-
-	return &emotionResult{emotion: emotionLabel, score: bestEmotion.Score}, nil
+	return body, nil
 }
 
 // parseEmotionResponse parses the emotion response from Hugging Face API
@@ -0,0 +1,156 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+// newEmotionServer is a fake inference endpoint. Batch requests ({"inputs": [...]})
+// are answered by batch; single ones get a joy score.
+func newEmotionServer(t *testing.T, batch func(w http.ResponseWriter, texts []string)) (*EmotionService, *atomic.Int32) {
+	t.Helper()
+
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+
+		var payload struct {
+			Inputs json.RawMessage `json:"inputs"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		var texts []string
+		if json.Unmarshal(payload.Inputs, &texts) == nil {
+			batch(w, texts)
+			return
+		}
+		json.NewEncoder(w).Encode(HuggingFaceResponse{{Label: "joy", Score: 0.9}})
+	}))
+	t.Cleanup(server.Close)
+
+	es := &EmotionService{
+		Client:    server.Client(),
+		Model:     "test",
+		Endpoints: []string{server.URL},
+		Retry:     RetryPolicy{MaxAttempts: 3},
+	}
+	return es, &requests
+}
+
+func TestClassifyBatchOneRequest(t *testing.T) {
+	es, requests := newEmotionServer(t, func(w http.ResponseWriter, texts []string) {
+		response := make([]HuggingFaceResponse, len(texts))
+		for i := range texts {
+			response[i] = HuggingFaceResponse{{Label: "sadness", Score: 0.8}, {Label: "joy", Score: 0.1}}
+		}
+		json.NewEncoder(w).Encode(response)
+	})
+
+	results, err := es.ClassifyBatch([]string{"a", "b", "c"})
+	if err != nil {
+		t.Fatalf("ClassifyBatch: %v", err)
+	}
+	if requests.Load() != 1 {
+		t.Errorf("made %d requests, want 1", requests.Load())
+	}
+	for i, result := range results {
+		if result.Label != "sad" || result.Scores["joy"] != 0.1 {
+			t.Errorf("result %d = %+v, want sad with the full distribution", i, result)
+		}
+	}
+}
+
+func TestClassifyBatchRetriesMissingTexts(t *testing.T) {
+	es, requests := newEmotionServer(t, func(w http.ResponseWriter, texts []string) {
+		response := make([]HuggingFaceResponse, len(texts))
+		for i := range texts {
+			if i != 1 {
+				response[i] = HuggingFaceResponse{{Label: "anger", Score: 0.7}}
+			}
+		}
+		json.NewEncoder(w).Encode(response)
+	})
+
+	results, err := es.ClassifyBatch([]string{"a", "b", "c"})
+	if err != nil {
+		t.Fatalf("ClassifyBatch: %v", err)
+	}
+	if requests.Load() != 2 {
+		t.Errorf("made %d requests, want the batch plus 1 single", requests.Load())
+	}
+	if results[0].Label != "angry" || results[1].Label != "happy" || results[2].Label != "angry" {
+		t.Errorf("got labels %q %q %q, want angry happy angry", results[0].Label, results[1].Label, results[2].Label)
+	}
+}
+
+func TestClassifyBatchUnparseableResponse(t *testing.T) {
+	es, requests := newEmotionServer(t, func(w http.ResponseWriter, texts []string) {
+		w.Write([]byte(`{"unexpected": true}`))
+	})
+
+	results, err := es.ClassifyBatch([]string{"a", "b"})
+	if err != nil {
+		t.Fatalf("ClassifyBatch: %v", err)
+	}
+	if requests.Load() != 3 {
+		t.Errorf("made %d requests, want the batch plus 2 singles", requests.Load())
+	}
+	if results[0].Label != "happy" || results[1].Label != "happy" {
+		t.Errorf("got %+v, want every text classified by single requests", results)
+	}
+}
+
+func TestClassifyBatchRequestFails(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   int
+		requests int32
+	}{
+		{"rate limited", http.StatusTooManyRequests, 3}, // retried by the policy, never per text
+		{"bad key", http.StatusUnauthorized, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			es, requests := newEmotionServer(t, func(w http.ResponseWriter, texts []string) {
+				http.Error(w, `{"error": "nope"}`, tt.status)
+			})
+			texts := []string{"a", "b", "c", "d"}
+
+			results, err := es.ClassifyBatch(texts)
+			var batchErr *BatchError
+			if !errors.As(err, &batchErr) {
+				t.Fatalf("error = %v, want a BatchError", err)
+			}
+			if len(batchErr.Failed) != len(texts) || len(results) != len(texts) {
+				t.Errorf("got %d failed of %d results, want all %d failed", len(batchErr.Failed), len(results), len(texts))
+			}
+			if requests.Load() != tt.requests {
+				t.Errorf("made %d requests, want %d", requests.Load(), tt.requests)
+			}
+
+			// the lexicon picks up the whole batch
+			fc := &fallbackClassifier{Primary: es, Fallback: NewLexiconClassifier()}
+			requests.Store(0)
+			results, err = fc.ClassifyBatch(texts)
+			if err != nil {
+				t.Fatalf("fallback ClassifyBatch: %v", err)
+			}
+			for i, result := range results {
+				if result.Model == es.Model || result.Label == "" {
+					t.Errorf("result %d = %+v, want a lexicon result", i, result)
+				}
+			}
+			if requests.Load() != tt.requests {
+				t.Errorf("fallback made %d requests, want %d", requests.Load(), tt.requests)
+			}
+		})
+	}
+}
@@ -0,0 +1,197 @@
+package services
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// FileStore persists events to an append-only JSON Lines file, one event per
+// line, and serves reads from memory. A line cut short by a crash is skipped
+// when the file is loaded. When events fall outside the retention limits, the
+// file is rewritten without them.
+type FileStore struct {
+	*MemoryStore
+	Path string
+
+	mu   sync.Mutex // serializes writes to file
+	file *os.File
+}
+
+// OpenFileStore loads the events saved at path that are within retention and
+// opens it for appending
+func OpenFileStore(path string, retention Retention) (*FileStore, error) {
+	fs := &FileStore{MemoryStore: NewMemoryStore(), Path: path}
+	fs.MemoryStore.Retention = retention
+	if err := fs.load(); err != nil {
+		return nil, err
+	}
+
+	if dir := filepath.Dir(path); dir != "." {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return nil, err
+		}
+	}
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	fs.file = file
+
+	// terminate a line cut short by a crash so the next event starts cleanly
+	if info, err := file.Stat(); err == nil && info.Size() > 0 && !fs.endsWithNewline(info.Size()) {
+		if _, err := file.Write([]byte{'\n'}); err != nil {
+			file.Close()
+			return nil, err
+		}
+	}
+
+	if err := fs.compact(time.Now()); err != nil {
+		log.Printf("Could not compact event store %s: %v", path, err)
+	}
+	return fs, nil
+}
+
+func (fs *FileStore) endsWithNewline(size int64) bool {
+	file, err := os.Open(fs.Path)
+	if err != nil {
+		return true
+	}
+	defer file.Close()
+
+	last := make([]byte, 1)
+	if _, err := file.ReadAt(last, size-1); err != nil {
+		return true
+	}
+	return last[0] == '\n'
+}
+
+func (fs *FileStore) load() error {
+	file, err := os.Open(fs.Path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	skipped := 0
+	fs.MemoryStore.mu.Lock()
+	defer fs.MemoryStore.mu.Unlock()
+	for scanner.Scan() {
+		var event EmotionEvent
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil || event.ID == "" {
+			skipped++
+			continue
+		}
+		fs.MemoryStore.add(event)
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	if skipped > 0 {
+		log.Printf("Skipped %d unreadable events in %s", skipped, fs.Path)
+	}
+	return nil
+}
+
+// Append writes the event to the file before making it visible to readers
+func (fs *FileStore) Append(event EmotionEvent) (EmotionEvent, error) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	if fs.file == nil {
+		return EmotionEvent{}, fmt.Errorf("event store %s is closed", fs.Path)
+	}
+
+	fs.MemoryStore.mu.Lock()
+	defer fs.MemoryStore.mu.Unlock()
+
+	event.ID = strconv.FormatUint(fs.MemoryStore.nextID, 10)
+	line, err := json.Marshal(event)
+	if err != nil {
+		return EmotionEvent{}, err
+	}
+	if _, err := fs.file.Write(append(line, '\n')); err != nil {
+		return EmotionEvent{}, err
+	}
+
+	fs.MemoryStore.add(event)
+	if now := time.Now(); fs.MemoryStore.Retention.due(fs.MemoryStore.events, now) {
+		if err := fs.compact(now); err != nil {
+			// the events are gone from memory, the next compaction drops them from the file
+			log.Printf("Could not compact event store %s: %v", fs.Path, err)
+		}
+	}
+	return event, nil
+}
+
+// compact trims expired events and rewrites the file without them. The new
+// file is written next to the old one and renamed over it, so a crash leaves
+// one or the other intact. Callers hold both locks, or own fs exclusively.
+func (fs *FileStore) compact(now time.Time) error {
+	if fs.MemoryStore.trim(now) == 0 {
+		return nil
+	}
+
+	tmpPath := fs.Path + ".tmp"
+	tmp, err := os.OpenFile(tmpPath, os.O_CREATE|os.O_TRUNC|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+
+	if err := fs.writeEvents(tmp); err != nil {
+		tmp.Close()
+		os.Remove(tmpPath)
+		return err
+	}
+	if err := os.Rename(tmpPath, fs.Path); err != nil {
+		tmp.Close()
+		os.Remove(tmpPath)
+		return err
+	}
+
+	// the new file's handle follows it through the rename
+	fs.file.Close()
+	fs.file = tmp
+	return nil
+}
+
+// writeEvents writes every event in memory to file and syncs it
+func (fs *FileStore) writeEvents(file *os.File) error {
+	writer := bufio.NewWriter(file)
+	for _, event := range fs.MemoryStore.events {
+		line, err := json.Marshal(event)
+		if err != nil {
+			return err
+		}
+		writer.Write(line)
+		writer.WriteByte('\n')
+	}
+	if err := writer.Flush(); err != nil {
+		return err
+	}
+	return file.Sync()
+}
+func (fs *FileStore) Close() error {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	if fs.file == nil {
+		return nil
+	}
+	err := fs.file.Close()
+	fs.file = nil
+	return err
+}
@@ -0,0 +1,293 @@
+package services
+
+import (
+	"embed"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+//go:embed data/countries.csv data/cities.csv data/regions.csv data/bounds.csv
+var gazetteerData embed.FS
+
+// Country is an ISO 3166-1 country with the coordinates of its centroid
+type Country struct {
+	Code   string // ISO 3166-1 alpha-2, lowercase
+	Name   string
+	Lat    float64
+	Lng    float64
+	Bounds *Bounds // nil when the gazetteer has no bounding box for it
+}
+
+// Bounds is a bounding box. Countries with far-flung territories (the US, France, ...)
+// are bounded by their mainland, so points spread within it stay on the map's main landmass.
+type Bounds struct {
+	MinLat, MinLng float64
+	MaxLat, MaxLng float64
+}
+
+// Contains reports whether a point is inside the box. A box with MinLng > MaxLng
+// crosses the antimeridian.
+func (b Bounds) Contains(lat, lng float64) bool {
+	if lat < b.MinLat || lat > b.MaxLat {
+		return false
+	}
+	if b.MinLng > b.MaxLng {
+		return lng >= b.MinLng || lng <= b.MaxLng
+	}
+	return lng >= b.MinLng && lng <= b.MaxLng
+}
+
+// Place kinds
+const (
+	PlaceKindCity   = "city"
+	PlaceKindRegion = "region" // state, province or other first-level division
+)
+
+// Place is a populated place or region from the gazetteer
+type Place struct {
+	Name        string
+	Kind        string
+	CountryCode string // ISO 3166-1 alpha-2, lowercase
+	Lat         float64
+	Lng         float64
+	Population  int // 0 for regions
+}
+
+// Gazetteer is an offline geocoder backed by embedded tables of every country
+// and major cities and regions. It implements the same contract as LocationService.GetCoordinates.
+type Gazetteer struct {
+	countries    map[string]*Country // by code
+	countryNames map[string]*Country // by normalized name or alias
+	places       map[string][]*Place // by normalized name or alias, cities first, most populous first
+	maxWords     int                 // longest place name or alias, in words
+}
+
+var (
+	defaultGazetteer     *Gazetteer
+	defaultGazetteerOnce sync.Once
+)
+
+// DefaultGazetteer returns the gazetteer loaded from the embedded tables
+func DefaultGazetteer() *Gazetteer {
+	defaultGazetteerOnce.Do(func() {
+		g, err := loadGazetteer()
+		if err != nil {
+			// the tables are embedded, so this only happens with a broken build
+			log.Printf("Could not load gazetteer: %v", err)
+			g = &Gazetteer{
+				countries:    make(map[string]*Country),
+				countryNames: make(map[string]*Country),
+				places:       make(map[string][]*Place),
+			}
+		}
+		defaultGazetteer = g
+	})
+	return defaultGazetteer
+}
+
+func loadGazetteer() (*Gazetteer, error) {
+	g := &Gazetteer{
+		countries:    make(map[string]*Country),
+		countryNames: make(map[string]*Country),
+		places:       make(map[string][]*Place),
+	}
+
+	err := readGazetteerTable("data/countries.csv", func(row []string) error {
+		lat, lng, err := parseLatLng(row[2], row[3])
+		if err != nil {
+			return err
+		}
+
+		country := &Country{Code: strings.ToLower(row[0]), Name: row[1], Lat: lat, Lng: lng}
+		g.countries[country.Code] = country
+		g.countryNames[normalizeQuery(country.Name)] = country
+		for _, alias := range splitAliases(row[4]) {
+			g.countryNames[normalizeQuery(alias)] = country
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = readGazetteerTable("data/cities.csv", func(row []string) error {
+		lat, lng, err := parseLatLng(row[2], row[3])
+		if err != nil {
+			return err
+		}
+		population, _ := strconv.Atoi(row[4])
+
+		place := &Place{Name: row[0], Kind: PlaceKindCity, CountryCode: strings.ToLower(row[1]), Lat: lat, Lng: lng, Population: population}
+		g.addPlace(place.Name, place)
+		for _, alias := range splitAliases(row[5]) {
+			g.addPlace(alias, place)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = readGazetteerTable("data/regions.csv", func(row []string) error {
+		lat, lng, err := parseLatLng(row[2], row[3])
+		if err != nil {
+			return err
+		}
+
+		place := &Place{Name: row[0], Kind: PlaceKindRegion, CountryCode: strings.ToLower(row[1]), Lat: lat, Lng: lng}
+		g.addPlace(place.Name, place)
+		for _, alias := range splitAliases(row[4]) {
+			g.addPlace(alias, place)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = readGazetteerTable("data/bounds.csv", func(row []string) error {
+		minLat, minLng, err := parseLatLng(row[1], row[2])
+		if err != nil {
+			return err
+		}
+		maxLat, maxLng, err := parseLatLng(row[3], row[4])
+		if err != nil {
+			return err
+		}
+
+		if country, ok := g.countries[strings.ToLower(row[0])]; ok {
+			country.Bounds = &Bounds{MinLat: minLat, MinLng: minLng, MaxLat: maxLat, MaxLng: maxLng}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, places := range g.places {
+		sort.SliceStable(places, func(i, j int) bool {
+			if places[i].Kind != places[j].Kind {
+				return places[i].Kind == PlaceKindCity
+			}
+			return places[i].Population > places[j].Population
+		})
+	}
+	return g, nil
+}
+
+func (g *Gazetteer) addPlace(name string, place *Place) {
+	key := normalizeQuery(name)
+	g.places[key] = append(g.places[key], place)
+	g.maxWords = max(g.maxWords, len(strings.Fields(key)))
+}
+
+// readGazetteerTable calls fn for every row of an embedded CSV table, skipping the header
+func readGazetteerTable(name string, fn func(row []string) error) error {
+	file, err := gazetteerData.Open(name)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	reader := csv.NewReader(file)
+	if _, err := reader.Read(); err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+
+	for {
+		row, err := reader.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		if err := fn(row); err != nil {
+			return fmt.Errorf("%s: %q: %w", name, row[0], err)
+		}
+	}
+}
+
+func parseLatLng(lat, lng string) (float64, float64, error) {
+	latFloat, err := strconv.ParseFloat(lat, 64)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to parse latitude: %w", err)
+	}
+	lngFloat, err := strconv.ParseFloat(lng, 64)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to parse longitude: %w", err)
+	}
+	return latFloat, lngFloat, nil
+}
+
+func splitAliases(value string) []string {
+	var aliases []string
+	for _, alias := range strings.Split(value, "|") {
+		if trimmed := strings.TrimSpace(alias); trimmed != "" {
+			aliases = append(aliases, trimmed)
+		}
+	}
+	return aliases
+}
+
+// Country finds a country by ISO code, name or alias
+func (g *Gazetteer) Country(nameOrCode string) (Country, bool) {
+	key := normalizeQuery(nameOrCode)
+	if country, ok := g.countries[key]; ok {
+		return *country, true
+	}
+	if country, ok := g.countryNames[key]; ok {
+		return *country, true
+	}
+	return Country{}, false
+}
+
+// Places returns every place with the given name or alias, cities first, most populous first
+func (g *Gazetteer) Places(name string) []Place {
+	matches := g.places[normalizeQuery(name)]
+	places := make([]Place, len(matches))
+	for i, place := range matches {
+		places[i] = *place
+	}
+	return places
+}
+
+// GetCoordinates resolves a city and/or country. A city unknown to the
+// gazetteer resolves to its country's centroid.
+func (g *Gazetteer) GetCoordinates(city, country string) (float64, float64, error) {
+	if city == "" && country == "" {
+		return 0, 0, fmt.Errorf("both city and country are empty")
+	}
+
+	// a lone name that is a country means the country, not a namesake town
+	if country == "" {
+		if resolved, ok := g.Country(city); ok {
+			return resolved.Lat, resolved.Lng, nil
+		}
+	}
+
+	resolved, countryOK := Country{}, false
+	if country != "" {
+		resolved, countryOK = g.Country(country)
+	}
+
+	if city != "" {
+		for _, place := range g.Places(city) {
+			if !countryOK || place.CountryCode == resolved.Code {
+				return place.Lat, place.Lng, nil
+			}
+		}
+	}
+
+	if countryOK {
+		return resolved.Lat, resolved.Lng, nil
+	}
+
+	return 0, 0, fmt.Errorf("%w for: %s", ErrLocationNotFound, strings.Trim(city+", "+country, ", "))
+}
@@ -0,0 +1,223 @@
+package services
+
+import (
+	"container/list"
+	"encoding/json"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// GeoCache is an LRU cache of geocoding results persisted to a JSON file, so
+// restarts don't re-query Nominatim. Misses ("no location found") are cached
+// too, with a shorter TTL.
+type GeoCache struct {
+	Capacity    int
+	TTL         time.Duration
+	NegativeTTL time.Duration
+	Path        string // empty disables persistence
+
+	mu     sync.Mutex
+	saveMu sync.Mutex
+	order  *list.List // front is most recently used
+	items  map[string]*list.Element
+}
+
+// geoCacheEntry is one cached query; Found is false for negative entries
+type geoCacheEntry struct {
+	Query   string    `json:"query"`
+	Lat     float64   `json:"lat"`
+	Lng     float64   `json:"lng"`
+	Found   bool      `json:"found"`
+	Expires time.Time `json:"expires"`
+}
+
+// NewGeoCache creates a cache configured from GEOCODE_CACHE_* variables and
+// loads previously saved entries
+func NewGeoCache() *GeoCache {
+	gc := &GeoCache{
+		Capacity:    1000,
+		TTL:         30 * 24 * time.Hour,
+		NegativeTTL: 24 * time.Hour,
+		Path:        filepath.Join("data", "geocode_cache.json"),
+		order:       list.New(),
+		items:       make(map[string]*list.Element),
+	}
+
+	if env, ok := os.LookupEnv("GEOCODE_CACHE_FILE"); ok {
+		gc.Path = env
+	}
+	if env := os.Getenv("GEOCODE_CACHE_SIZE"); env != "" {
+		if parsed, err := strconv.Atoi(env); err == nil && parsed > 0 {
+			gc.Capacity = parsed
+		}
+	}
+	if env := os.Getenv("GEOCODE_CACHE_TTL"); env != "" {
+		if parsed, err := time.ParseDuration(env); err == nil && parsed > 0 {
+			gc.TTL = parsed
+		}
+	}
+	if env := os.Getenv("GEOCODE_NEGATIVE_TTL"); env != "" {
+		if parsed, err := time.ParseDuration(env); err == nil && parsed > 0 {
+			gc.NegativeTTL = parsed
+		}
+	}
+
+	if err := gc.load(); err != nil {
+		log.Printf("Could not load geocode cache from %s: %v", gc.Path, err)
+	}
+	return gc
+}
+
+// Get returns the cached entry for a query. found is false for cached misses.
+func (gc *GeoCache) Get(query string) (lat, lng float64, found, ok bool) {
+	key := normalizeQuery(query)
+
+	gc.mu.Lock()
+	defer gc.mu.Unlock()
+
+	elem, ok := gc.items[key]
+	if !ok {
+		return 0, 0, false, false
+	}
+
+	entry := elem.Value.(*geoCacheEntry)
+	if time.Now().After(entry.Expires) {
+		gc.order.Remove(elem)
+		delete(gc.items, key)
+		return 0, 0, false, false
+	}
+
+	gc.order.MoveToFront(elem)
+	return entry.Lat, entry.Lng, entry.Found, true
+}
+
+// Put caches coordinates for a query
+func (gc *GeoCache) Put(query string, lat, lng float64) {
+	gc.put(&geoCacheEntry{Query: normalizeQuery(query), Lat: lat, Lng: lng, Found: true, Expires: time.Now().Add(gc.TTL)})
+}
+
+// PutMissing caches that a query has no location
+func (gc *GeoCache) PutMissing(query string) {
+	gc.put(&geoCacheEntry{Query: normalizeQuery(query), Expires: time.Now().Add(gc.NegativeTTL)})
+}
+
+func (gc *GeoCache) put(entry *geoCacheEntry) {
+	gc.mu.Lock()
+	if elem, ok := gc.items[entry.Query]; ok {
+		elem.Value = entry
+		gc.order.MoveToFront(elem)
+	} else {
+		gc.items[entry.Query] = gc.order.PushFront(entry)
+		for gc.order.Len() > gc.Capacity {
+			oldest := gc.order.Back()
+			gc.order.Remove(oldest)
+			delete(gc.items, oldest.Value.(*geoCacheEntry).Query)
+		}
+	}
+	gc.mu.Unlock()
+
+	gc.persist()
+}
+
+// persist saves the current contents; saves are serialized so an older
+// snapshot never overwrites a newer one
+func (gc *GeoCache) persist() {
+	gc.saveMu.Lock()
+	defer gc.saveMu.Unlock()
+
+	gc.mu.Lock()
+	entries := gc.entries()
+	gc.mu.Unlock()
+
+	if err := gc.save(entries); err != nil {
+		log.Printf("Could not save geocode cache to %s: %v", gc.Path, err)
+	}
+}
+
+// entries returns the cache contents, most recently used first; callers hold mu
+func (gc *GeoCache) entries() []geoCacheEntry {
+	entries := make([]geoCacheEntry, 0, gc.order.Len())
+	for elem := gc.order.Front(); elem != nil; elem = elem.Next() {
+		entries = append(entries, *elem.Value.(*geoCacheEntry))
+	}
+	return entries
+}
+
+func (gc *GeoCache) load() error {
+	if gc.Path == "" {
+		return nil
+	}
+
+	data, err := os.ReadFile(gc.Path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	var entries []geoCacheEntry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return err
+	}
+
+	gc.mu.Lock()
+	defer gc.mu.Unlock()
+
+	now := time.Now()
+	for i := range entries {
+		entry := entries[i]
+		if now.After(entry.Expires) || gc.order.Len() >= gc.Capacity {
+			continue
+		}
+		if _, ok := gc.items[entry.Query]; !ok {
+			// the file is most recently used first
+			gc.items[entry.Query] = gc.order.PushBack(&entry)
+		}
+	}
+	return nil
+}
+
+// save writes the entries to a temp file and renames it over the cache file,
+// so a crash never leaves a half-written cache behind
+func (gc *GeoCache) save(entries []geoCacheEntry) error {
+	if gc.Path == "" {
+		return nil
+	}
+
+	data, err := json.Marshal(entries)
+	if err != nil {
+		return err
+	}
+
+	if dir := filepath.Dir(gc.Path); dir != "." {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return err
+		}
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(gc.Path), ".geocode-*.tmp")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), gc.Path)
+}
+
+// normalizeQuery lowercases a query and collapses whitespace
+func normalizeQuery(query string) string {
+	return strings.Join(strings.Fields(strings.ToLower(query)), " ")
+}
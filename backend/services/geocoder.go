@@ -0,0 +1,41 @@
+package services
+
+import (
+	"log"
+	"os"
+	"strings"
+)
+
+// Geocoder resolves a city and/or country to coordinates
+type Geocoder interface {
+	GetCoordinates(city, country string) (float64, float64, error)
+}
+
+// fallbackGeocoder uses Fallback whenever Primary fails
+type fallbackGeocoder struct {
+	Primary  Geocoder
+	Fallback Geocoder
+}
+
+func (fg *fallbackGeocoder) GetCoordinates(city, country string) (float64, float64, error) {
+	lat, lng, err := fg.Primary.GetCoordinates(city, country)
+	if err != nil {
+		log.Printf("Geocoding %s, %s failed, falling back to gazetteer: %v", city, country, err)
+		return fg.Fallback.GetCoordinates(city, country)
+	}
+	return lat, lng, nil
+}
+
+// NewGeocoderFromEnv creates the geocoder named by GEOCODER: "nominatim"
+// (default, falls back to the gazetteer) or "gazetteer" (offline only)
+func NewGeocoderFromEnv(locationService *LocationService) Geocoder {
+	switch strings.ToLower(os.Getenv("GEOCODER")) {
+	case "gazetteer", "offline":
+		return DefaultGazetteer()
+	case "", "nominatim":
+		return &fallbackGeocoder{Primary: locationService, Fallback: DefaultGazetteer()}
+	default:
+		log.Printf("Unknown GEOCODER %q, using nominatim", os.Getenv("GEOCODER"))
+		return &fallbackGeocoder{Primary: locationService, Fallback: DefaultGazetteer()}
+	}
+}
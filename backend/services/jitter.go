@@ -0,0 +1,101 @@
+package services
+
+import (
+	"hash/fnv"
+	"log"
+	"math"
+	"math/rand/v2"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Jitter modes
+const (
+	JitterOff    = "off"
+	JitterRadius = "radius" // within RadiusKm of the location
+	JitterBounds = "bbox"   // country-level points anywhere in the country's bounding box
+)
+
+const kmPerDegree = 111.32
+
+// Jitter spreads points that share coordinates, e.g. every article about a
+// country landing on its centroid, so markers don't stack. Offsets are seeded
+// by article ID, so the same article always lands on the same spot.
+type Jitter struct {
+	Mode      string
+	RadiusKm  float64
+	Gazetteer *Gazetteer
+}
+
+// NewJitterFromEnv reads LOCATION_JITTER and LOCATION_JITTER_RADIUS_KM
+func NewJitterFromEnv() *Jitter {
+	jitter := &Jitter{
+		Mode:      JitterOff,
+		RadiusKm:  25,
+		Gazetteer: DefaultGazetteer(),
+	}
+
+	switch mode := strings.ToLower(os.Getenv("LOCATION_JITTER")); mode {
+	case "", JitterOff, "false":
+	case JitterRadius, JitterBounds:
+		jitter.Mode = mode
+	default:
+		log.Printf("Unknown LOCATION_JITTER %q, not spreading points", mode)
+	}
+
+	if env := os.Getenv("LOCATION_JITTER_RADIUS_KM"); env != "" {
+		if parsed, err := strconv.ParseFloat(env, 64); err == nil && parsed > 0 {
+			jitter.RadiusKm = parsed
+		}
+	}
+	return jitter
+}
+
+// Apply returns the location's coordinates offset deterministically for the
+// given article. In bbox mode city and region points, and countries without a
+// bounding box, are spread within the radius instead.
+func (j *Jitter) Apply(articleID string, location LocationData) (float64, float64) {
+	if j == nil || j.Mode == JitterOff {
+		return location.Lat, location.Lng
+	}
+
+	rng := jitterRand(articleID, location)
+
+	if j.Mode == JitterBounds && location.Precision == PrecisionCountry {
+		if country, ok := j.Gazetteer.Country(location.Country); ok && country.Bounds != nil {
+			b := country.Bounds
+			return b.MinLat + rng.Float64()*(b.MaxLat-b.MinLat),
+				b.MinLng + rng.Float64()*(b.MaxLng-b.MinLng)
+		}
+	}
+
+	// uniform over the disc: sqrt keeps points from bunching in the middle
+	distance := j.RadiusKm * math.Sqrt(rng.Float64())
+	bearing := 2 * math.Pi * rng.Float64()
+
+	lat := location.Lat + distance*math.Cos(bearing)/kmPerDegree
+	lng := location.Lng + distance*math.Sin(bearing)/(kmPerDegree*math.Max(math.Cos(location.Lat*math.Pi/180), 0.01))
+	return math.Max(math.Min(lat, 90), -90), wrapLongitude(lng)
+}
+
+// jitterRand seeds a generator from the article and location, so an article
+// placed in several countries gets an independent offset in each
+func jitterRand(articleID string, location LocationData) *rand.Rand {
+	h := fnv.New64a()
+	h.Write([]byte(articleID))
+	h.Write([]byte{0})
+	h.Write([]byte(location.String()))
+	seed := h.Sum64()
+	return rand.New(rand.NewPCG(seed, seed>>1^0x9e3779b97f4a7c15))
+}
+
+func wrapLongitude(lng float64) float64 {
+	for lng > 180 {
+		lng -= 360
+	}
+	for lng < -180 {
+		lng += 360
+	}
+	return lng
+}
@@ -0,0 +1,234 @@
+package services
+
+import (
+	"strings"
+	"unicode"
+)
+
+// LexiconClassifier is an offline emotion classifier based on a word-emotion lexicon.
+// It scores text by summing the weights of emotion words, flipping words that
+// follow a negation and scaling words that follow an intensifier. Labels use the
+// same names as the Hugging Face model (joy, sadness, anger, fear, surprise, neutral).
+type LexiconClassifier struct {
+	Lexicon map[string]lexiconEntry
+
+	// NeutralBias is the score neutral starts with, so text with only a weak
+	// emotional signal stays neutral. It must be below the weight of a strong
+	// word, since neutral wins ties.
+	NeutralBias float64
+}
+
+type lexiconEntry struct {
+	Label  string
+	Weight float64
+}
+
+// NewLexiconClassifier creates a classifier with the built-in lexicon
+func NewLexiconClassifier() *LexiconClassifier {
+	lexicon := make(map[string]lexiconEntry)
+	for label, words := range defaultLexicon {
+		for word, weight := range words {
+			lexicon[word] = lexiconEntry{Label: label, Weight: weight}
+		}
+	}
+
+	return &LexiconClassifier{
+		Lexicon:     lexicon,
+		NeutralBias: 0.4,
+	}
+}
+
+// negationWindow is how many tokens after a negation are affected by it
+const negationWindow = 3
+
+var negations = map[string]bool{
+	"not": true, "no": true, "never": true, "none": true, "nobody": true,
+	"nothing": true, "neither": true, "nor": true, "without": true,
+	"hardly": true, "barely": true, "cannot": true, "lack": true,
+}
+
+var intensifiers = map[string]float64{
+	"very": 1.5, "extremely": 2.0, "deeply": 1.6, "highly": 1.5, "really": 1.3,
+	"so": 1.3, "incredibly": 1.8, "utterly": 1.8, "totally": 1.5, "completely": 1.5,
+	"absolutely": 1.7, "most": 1.3, "major": 1.3, "massive": 1.6, "huge": 1.4,
+	"severe": 1.5, "severely": 1.5, "record": 1.3, "historic": 1.3,
+	"slightly": 0.5, "somewhat": 0.6, "mildly": 0.5, "little": 0.6, "partly": 0.6,
+}
+
+// negatedLabel is where an emotion word's weight goes when it is negated
+var negatedLabel = map[string]string{
+	"joy":      "sadness",
+	"sadness":  "joy",
+	"anger":    "neutral",
+	"fear":     "neutral",
+	"surprise": "neutral",
+}
+
+// Name implements EmotionClassifier
+func (lc *LexiconClassifier) Name() string {
+	return "lexicon"
+}
+
+// Classify implements EmotionClassifier
+func (lc *LexiconClassifier) Classify(text string) (EmotionResult, error) {
+	scores := lc.Scores(text)
+
+	emotions := make(HuggingFaceResponse, 0, len(lexiconLabels))
+	for _, label := range lexiconLabels {
+		emotions = append(emotions, EmotionResponse{Label: label, Score: scores[label]})
+	}
+	return newEmotionResult(lc.Name(), emotions), nil
+}
+
+// ClassifyBatch implements EmotionClassifier
+func (lc *LexiconClassifier) ClassifyBatch(texts []string) ([]EmotionResult, error) {
+	return classifyEach(lc, texts)
+}
+
+// lexiconLabels is the order labels are compared in, so ties are deterministic
+var lexiconLabels = []string{"neutral", "joy", "sadness", "anger", "fear", "surprise"}
+
+// Scores returns the probability of every label; they sum to 1
+func (lc *LexiconClassifier) Scores(text string) map[string]float64 {
+	raw := map[string]float64{"neutral": lc.NeutralBias}
+
+	negatedFor := 0
+	multiplier := 1.0
+	for _, token := range tokenize(text) {
+		if negations[token] || strings.HasSuffix(token, "n't") {
+			negatedFor = negationWindow
+			continue
+		}
+		if factor, ok := intensifiers[token]; ok {
+			multiplier *= factor
+			continue
+		}
+
+		entry, ok := lc.lookup(token)
+		if ok {
+			label := entry.Label
+			weight := entry.Weight * multiplier
+			if negatedFor > 0 {
+				// "not happy" is weaker evidence of sadness than "sad"
+				label = negatedLabel[label]
+				weight *= 0.5
+			}
+			raw[label] += weight
+		}
+
+		multiplier = 1.0
+		if negatedFor > 0 {
+			negatedFor--
+		}
+	}
+
+	total := 0.0
+	for _, score := range raw {
+		total += score
+	}
+
+	scores := make(map[string]float64, len(lexiconLabels))
+	for _, label := range lexiconLabels {
+		scores[label] = raw[label] / total
+	}
+	return scores
+}
+
+// lookup finds a token in the lexicon, trying a few common suffixes
+func (lc *LexiconClassifier) lookup(token string) (lexiconEntry, bool) {
+	if entry, ok := lc.Lexicon[token]; ok {
+		return entry, true
+	}
+	for _, suffix := range []string{"s", "es", "ed", "d", "ing", "ly", "ness"} {
+		if stem, ok := strings.CutSuffix(token, suffix); ok && len(stem) > 2 {
+			if entry, ok := lc.Lexicon[stem]; ok {
+				return entry, true
+			}
+			// "angered" -> "anger", "celebrating" -> "celebrate"
+			if entry, ok := lc.Lexicon[stem+"e"]; ok {
+				return entry, true
+			}
+		}
+	}
+	return lexiconEntry{}, false
+}
+
+// tokenize lowercases text and splits it into words, keeping apostrophes.
+// Typographic apostrophes, common in feeds, become ASCII ones so "don’t" negates.
+func tokenize(text string) []string {
+	text = strings.ReplaceAll(strings.ToLower(text), "’", "'")
+	return strings.FieldsFunc(text, func(r rune) bool {
+		return !unicode.IsLetter(r) && r != '\''
+	})
+}
+
+// defaultLexicon maps Hugging Face labels to words and their weights, tuned for news headlines
+var defaultLexicon = map[string]map[string]float64{
+	"joy": {
+		"happy": 1.0, "joy": 1.0, "joyful": 1.0, "celebrate": 1.0, "celebration": 1.0,
+		"win": 0.8, "won": 0.8, "victory": 1.0, "triumph": 1.0, "success": 0.8,
+		"successful": 0.8, "love": 1.0, "loved": 1.0, "hope": 0.6, "hopeful": 0.7,
+		"delight": 1.0, "delighted": 1.0, "glad": 0.8, "pleased": 0.7, "proud": 0.8,
+		"excited": 0.9, "exciting": 0.8, "thrilled": 1.0, "wonderful": 1.0, "great": 0.6,
+		"good": 0.5, "best": 0.6, "amazing": 0.9, "award": 0.6, "honor": 0.6,
+		"peace": 0.7, "rescue": 0.6, "rescued": 0.7, "recover": 0.6, "recovery": 0.6,
+		"growth": 0.5, "boost": 0.5, "breakthrough": 0.8, "cure": 0.8,
+		"festival": 0.6, "wedding": 0.7, "champion": 0.8, "championship": 0.6, "gold": 0.5,
+		"smile": 0.8, "laugh": 0.8, "cheer": 0.8, "praise": 0.7, "welcome": 0.5,
+		"improve": 0.5, "improvement": 0.5, "benefit": 0.5, "thank": 0.6, "grateful": 0.9,
+		"reunite": 0.8, "reunited": 0.9, "agreement": 0.4, "deal": 0.3, "safe": 0.5,
+		"beautiful": 0.8, "fun": 0.7, "enjoy": 0.8, "optimism": 0.8, "optimistic": 0.8,
+		"rally": 0.3, "soar": 0.6, "surge": 0.3, "gain": 0.4, "profit": 0.4,
+	},
+	"sadness": {
+		"sad": 1.0, "sadness": 1.0, "grief": 1.0, "grieve": 1.0, "mourn": 1.0,
+		"mourning": 1.0, "death": 0.9, "dead": 0.9, "die": 0.9, "died": 0.9,
+		"dies": 0.9, "killed": 0.9, "loss": 0.8, "lost": 0.6, "lose": 0.6,
+		"tragedy": 1.0, "tragic": 1.0, "funeral": 1.0, "victim": 0.8, "victims": 0.8,
+		"sorrow": 1.0, "heartbreak": 1.0, "heartbroken": 1.0, "cry": 0.8, "tears": 0.8,
+		"unemployment": 0.6, "poverty": 0.7, "hunger": 0.7, "famine": 0.9, "layoff": 0.7,
+		"layoffs": 0.7, "decline": 0.5, "fall": 0.3, "fell": 0.3, "drop": 0.3,
+		"recession": 0.7, "bankrupt": 0.7, "bankruptcy": 0.7, "closure": 0.5, "fail": 0.6,
+		"failure": 0.7, "defeat": 0.7, "disappoint": 0.8, "disappointing": 0.8, "lonely": 0.9,
+		"miss": 0.4, "missing": 0.6, "suffer": 0.8, "suffering": 0.9, "pain": 0.7,
+		"injured": 0.6, "wounded": 0.7, "casualties": 0.9, "toll": 0.7, "displaced": 0.7,
+		"homeless": 0.7, "refugee": 0.5, "orphan": 0.8, "depression": 0.9, "despair": 1.0,
+		"sorry": 0.6, "regret": 0.7, "cancel": 0.4, "cancelled": 0.5, "poor": 0.5,
+	},
+	"anger": {
+		"angry": 1.0, "anger": 1.0, "rage": 1.0, "furious": 1.0, "fury": 1.0,
+		"outrage": 1.0, "outraged": 1.0, "protest": 0.8, "protester": 0.7, "riot": 0.9,
+		"clash": 0.8, "attack": 0.8, "assault": 0.9, "fight": 0.7, "war": 0.7,
+		"violence": 0.9, "violent": 0.9, "accuse": 0.7, "blame": 0.7, "condemn": 0.9,
+		"slam": 0.8, "criticize": 0.6, "criticism": 0.6, "backlash": 0.8, "furor": 0.9,
+		"hate": 1.0, "hatred": 1.0, "hostile": 0.8, "corrupt": 0.8, "corruption": 0.8,
+		"fraud": 0.8, "scandal": 0.8, "abuse": 0.9, "strike": 0.5, "boycott": 0.7,
+		"sanction": 0.5, "threat": 0.6, "threaten": 0.7, "dispute": 0.6, "conflict": 0.7,
+		"revenge": 0.9, "retaliate": 0.8, "retaliation": 0.8, "frustrated": 0.8, "frustration": 0.8,
+		"annoyed": 0.7, "furiously": 1.0, "unfair": 0.7, "injustice": 0.9, "betray": 0.9,
+		"betrayal": 0.9, "insult": 0.8, "demand": 0.4, "reject": 0.5, "defy": 0.6,
+		"crackdown": 0.8, "arrest": 0.5, "jail": 0.5, "shooting": 0.7, "murder": 0.9,
+	},
+	"fear": {
+		"fear": 1.0, "afraid": 1.0, "scared": 1.0, "terror": 1.0, "terrorist": 0.9,
+		"terrifying": 1.0, "panic": 1.0, "anxiety": 0.9, "anxious": 0.9, "worry": 0.8,
+		"worried": 0.8, "concern": 0.6, "concerned": 0.6, "alarm": 0.8, "warning": 0.7,
+		"warn": 0.7, "risk": 0.6, "danger": 0.9, "dangerous": 0.9, "threatened": 0.8,
+		"crisis": 0.8, "emergency": 0.8, "evacuate": 0.9, "evacuation": 0.9, "disaster": 0.9,
+		"earthquake": 0.9, "hurricane": 0.9, "flood": 0.8, "wildfire": 0.9, "storm": 0.6,
+		"outbreak": 0.9, "pandemic": 0.8, "virus": 0.6, "epidemic": 0.8, "infection": 0.6,
+		"nuclear": 0.6, "missile": 0.7, "bomb": 0.9, "explosion": 0.8, "hostage": 0.9,
+		"kidnap": 0.9, "kidnapped": 0.9, "uncertain": 0.6, "uncertainty": 0.6, "volatile": 0.6,
+		"collapse": 0.7, "crash": 0.7, "threats": 0.7, "fearful": 1.0, "dread": 1.0,
+		"horror": 1.0, "nightmare": 0.9, "menace": 0.8, "vulnerable": 0.6, "unsafe": 0.8,
+	},
+	"surprise": {
+		"surprise": 1.0, "surprised": 1.0, "surprising": 1.0, "shock": 1.0, "shocked": 1.0,
+		"shocking": 1.0, "unexpected": 1.0, "unexpectedly": 1.0, "sudden": 0.8, "suddenly": 0.8,
+		"astonish": 1.0, "astonishing": 1.0, "stunned": 1.0, "stunning": 0.9, "amazed": 0.9,
+		"unprecedented": 0.9, "rare": 0.6, "bizarre": 0.8, "strange": 0.7, "mystery": 0.7,
+		"mysterious": 0.7, "reveal": 0.6, "revealed": 0.6, "discover": 0.7, "discovery": 0.7,
+		"unveil": 0.6, "upset": 0.6, "twist": 0.8, "wow": 1.0, "incredible": 0.8,
+		"unbelievable": 0.9, "first": 0.2, "odd": 0.6, "remarkable": 0.7, "abrupt": 0.7,
+	},
+}
@@ -0,0 +1,69 @@
+package services
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestLexiconClassify(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+	}{
+		// a single strong word beats the neutral bias
+		{"Thousands killed in devastating earthquake", "sad"},
+		{"Family reunited after decades", "happy"},
+		{"Protesters furious over new law", "angry"},
+
+		// weak signals stay neutral
+		{"Council meets on Tuesday", "neutral"},
+		{"Trade deal signed", "neutral"},
+
+		// negation
+		{"Fans are not happy", "sad"},
+		{"I don't love this plan", "sad"},
+		{"I don’t love this plan", "sad"}, // typographic apostrophe
+		{"Officials never condemn the vote", "neutral"},
+		{"Not the best season, but we celebrate", "happy"}, // negation only reaches 3 words
+
+		// intensifiers
+		{"Good news", "happy"},
+		{"Slightly good news", "neutral"},
+		{"Very sad but happy", "sad"},
+		{"Sad but very happy", "happy"},
+
+		// ties go to the first label in lexiconLabels
+		{"Happy and sad", "happy"},
+		{"Agreement reached", "neutral"},
+	}
+
+	lc := NewLexiconClassifier()
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			result, err := lc.Classify(tt.text)
+			if err != nil {
+				t.Fatalf("Classify: %v", err)
+			}
+			if result.Label != tt.want {
+				t.Errorf("Classify(%q) = %s (%.2f), want %s", tt.text, result.Label, result.Score, tt.want)
+			}
+		})
+	}
+}
+
+func TestTokenize(t *testing.T) {
+	tests := []struct {
+		text string
+		want []string
+	}{
+		{"Don't panic!", []string{"don't", "panic"}},
+		{"Don’t panic!", []string{"don't", "panic"}},
+		{"Kyiv’s mayor, 2025", []string{"kyiv's", "mayor"}},
+	}
+
+	for _, tt := range tests {
+		if got := tokenize(tt.text); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("tokenize(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
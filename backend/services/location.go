@@ -2,29 +2,106 @@ package services
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"log"
 	"net/http"
+	"net/url"
+	"os"
 	"strings"
+	"sync"
 	"time"
 )
 
+// LocationData is where an article is about. Lat and Lng are only set at city
+// and region precision; country precision still needs geocoding.
 type LocationData struct {
-	City    string
-	Country string
-	Lat     float64
-	Lng     float64
+	City        string
+	Region      string
+	Country     string
+	CountryCode string // ISO 3166-1 alpha-2, lowercase; empty when the country is unknown
+	Lat         float64
+	Lng         float64
+	Precision   string
 }
 
+func (l LocationData) String() string {
+	var parts []string
+	for _, part := range []string{l.City, l.Region, l.Country} {
+		if part != "" {
+			parts = append(parts, part)
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// Location precisions, most to least specific
+const (
+	PrecisionCity    = "city"
+	PrecisionRegion  = "region"
+	PrecisionCountry = "country"
+)
+
+// ErrLocationNotFound is returned when a query has no geocoding result
+var ErrLocationNotFound = errors.New("no location found")
+
 type LocationService struct {
-	Client *http.Client
+	Client    *http.Client
+	Retry     RetryPolicy
+	Cache     *GeoCache
+	BaseURL   string // Nominatim instance, e.g. a self-hosted one
+	UserAgent string // identifies the app, required by the Nominatim usage policy
+	Email     string // contact address sent with every request, optional
+	Limiter   *RateLimiter
+	Gazetteer *Gazetteer // used to find places mentioned in article text
+	Policy    string     // LocationPolicy* for articles tagged with several countries
+
+	mu       sync.Mutex
+	inflight map[string]*geocodeCall
+}
+
+// geocodeCall is a Nominatim query in progress that identical queries wait on
+type geocodeCall struct {
+	done     chan struct{}
+	lat, lng float64
+	err      error
 }
 
+// NewLocationService creates a Nominatim client configured from NOMINATIM_* variables.
+// Every client for the same Nominatim host shares one rate limiter
+// (1 request/second by default, per the public instance's usage policy).
 func NewLocationService() *LocationService {
+	baseURL := strings.TrimRight(os.Getenv("NOMINATIM_URL"), "/")
+	if baseURL == "" {
+		baseURL = "https://nominatim.openstreetmap.org"
+	}
+
+	userAgent := os.Getenv("NOMINATIM_USER_AGENT")
+	if userAgent == "" {
+		userAgent = "Emotisphere/1.0"
+	}
+
+	interval := time.Second
+	if env := os.Getenv("NOMINATIM_RATE_INTERVAL"); env != "" {
+		if parsed, err := time.ParseDuration(env); err == nil && parsed >= 0 {
+			interval = parsed
+		}
+	}
+
 	return &LocationService{
 		Client: &http.Client{
 			Timeout: 10 * time.Second,
 		},
+		Retry:     NewRetryPolicy(),
+		Cache:     NewGeoCache(),
+		BaseURL:   baseURL,
+		UserAgent: userAgent,
+		Email:     os.Getenv("NOMINATIM_EMAIL"),
+		Limiter:   sharedRateLimiter(baseURL, interval),
+		Gazetteer: DefaultGazetteer(),
+		Policy:    locationPolicy(),
+		inflight:  make(map[string]*geocodeCall),
 	}
 }
 
@@ -44,30 +121,68 @@ func (ls *LocationService) GetCoordinates(city, country string) (float64, float6
 		return 0, 0, fmt.Errorf("both city and country are empty")
 	}
 
-	// Nominatim API (free, no API key required)
-	url := fmt.Sprintf("https://nominatim.openstreetmap.org/search?q=%s&format=json&limit=1",
-		strings.ReplaceAll(query, " ", "+"))
-
-	req, err := http.NewRequest("GET", url, nil)
-	if err != nil {
-		return 0, 0, fmt.Errorf("failed to create request: %w", err)
+	if ls.Cache != nil {
+		if lat, lng, found, ok := ls.Cache.Get(query); ok {
+			if !found {
+				return 0, 0, fmt.Errorf("%w for: %s (cached)", ErrLocationNotFound, query)
+			}
+			return lat, lng, nil
+		}
 	}
 
-	req.Header.Set("User-Agent", "Emotisphere/1.0")
+	return ls.coalesce(query)
+}
 
-	resp, err := ls.Client.Do(req)
-	if err != nil {
-		return 0, 0, fmt.Errorf("failed to make request: %w", err)
+// coalesce makes one Nominatim request for identical concurrent queries
+func (ls *LocationService) coalesce(query string) (float64, float64, error) {
+	key := normalizeQuery(query)
+
+	ls.mu.Lock()
+	if call, ok := ls.inflight[key]; ok {
+		ls.mu.Unlock()
+		<-call.done
+		return call.lat, call.lng, call.err
 	}
-	defer resp.Body.Close()
+	call := &geocodeCall{done: make(chan struct{})}
+	ls.inflight[key] = call
+	ls.mu.Unlock()
 
-	if resp.StatusCode != http.StatusOK {
-		return 0, 0, fmt.Errorf("Nominatim API returned status %d", resp.StatusCode)
+	call.lat, call.lng, call.err = ls.lookup(query)
+	if ls.Cache != nil {
+		if call.err == nil {
+			ls.Cache.Put(query, call.lat, call.lng)
+		} else if errors.Is(call.err, ErrLocationNotFound) {
+			ls.Cache.PutMissing(query)
+		}
 	}
 
-	body, err := io.ReadAll(resp.Body)
+	ls.mu.Lock()
+	delete(ls.inflight, key)
+	ls.mu.Unlock()
+	close(call.done)
+
+	return call.lat, call.lng, call.err
+}
+
+// lookup queries Nominatim
+func (ls *LocationService) lookup(query string) (float64, float64, error) {
+	params := url.Values{}
+	params.Set("q", query)
+	params.Set("format", "json")
+	params.Set("limit", "1")
+	if ls.Email != "" {
+		params.Set("email", ls.Email)
+	}
+	searchURL := ls.BaseURL + "/search?" + params.Encode()
+
+	var body []byte
+	err := ls.Retry.Do(func() error {
+		var err error
+		body, err = ls.search(searchURL)
+		return err
+	})
 	if err != nil {
-		return 0, 0, fmt.Errorf("failed to read response: %w", err)
+		return 0, 0, err
 	}
 
 	var results []map[string]interface{}
@@ -76,7 +191,7 @@ func (ls *LocationService) GetCoordinates(city, country string) (float64, float6
 	}
 
 	if len(results) == 0 {
-		return 0, 0, fmt.Errorf("no location found for: %s", query)
+		return 0, 0, fmt.Errorf("%w for: %s", ErrLocationNotFound, query)
 	}
 
 	result := results[0]
@@ -98,38 +213,139 @@ func (ls *LocationService) GetCoordinates(city, country string) (float64, float6
 	return latFloat, lngFloat, nil
 }
 
-func (ls *LocationService) ProcessLocation(countries []string) (string, string, error) {
-	// extract first country if available
-	if len(countries) > 0 && countries[0] != "" {
-		countryName := mapCountryCode(countries[0])
-		return "", countryName, nil
+// search makes a single Nominatim request
+func (ls *LocationService) search(searchURL string) ([]byte, error) {
+	req, err := http.NewRequest("GET", searchURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("User-Agent", ls.UserAgent)
+	if ls.Email != "" {
+		req.Header.Set("From", ls.Email)
+	}
+
+	if ls.Limiter != nil {
+		ls.Limiter.Wait()
+	}
+
+	resp, err := ls.Client.Do(req)
+	if err != nil {
+		return nil, networkError("nominatim", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, networkError("nominatim", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, newAPIError("nominatim", resp, body)
+	}
+
+	return body, nil
+}
+
+// Location policies for articles tagged with several countries
+const (
+	LocationPolicyFirst        = "first"         // only the first tagged country
+	LocationPolicyAll          = "all"           // one location per tagged country
+	LocationPolicyMostSpecific = "most-specific" // the most mentioned place in any tagged country
+)
+
+// ProcessLocation works out where an article is about. Within a country that is
+// the city or region its text mentions most, or the country itself when the text
+// names no known place. Policy decides how many locations an article tagged with
+// several countries gets; there is always at least one.
+func (ls *LocationService) ProcessLocation(article NewsArticle) ([]LocationData, error) {
+	codes := ls.countryCodes(article.Country)
+	if len(codes) == 0 {
+		// untagged, or tagged with something that isn't a country: the text may
+		// still name a place
+		if place, ok := ls.Gazetteer.ExtractPlace(article, nil); ok {
+			return []LocationData{placeLocation(place)}, nil
+		}
+		if len(article.Country) > 0 && article.Country[0] != "" {
+			return []LocationData{{Country: mapCountryCode(article.Country[0]), Precision: PrecisionCountry}}, nil
+		}
+		return []LocationData{{Country: "United States", CountryCode: "us", Precision: PrecisionCountry}}, nil
+	}
+
+	switch ls.Policy {
+	case LocationPolicyFirst:
+		return []LocationData{ls.locate(article, codes[:1])}, nil
+	case LocationPolicyAll:
+		locations := make([]LocationData, 0, len(codes))
+		for _, code := range codes {
+			locations = append(locations, ls.locate(article, []string{code}))
+		}
+		return locations, nil
+	default:
+		return []LocationData{ls.locate(article, codes)}, nil
+	}
+}
+
+// locate finds the place the article mentions most within the given countries,
+// falling back to the first of them
+func (ls *LocationService) locate(article NewsArticle, codes []string) LocationData {
+	place, ok := ls.Gazetteer.ExtractPlace(article, codes)
+	if !ok {
+		return LocationData{Country: mapCountryCode(codes[0]), CountryCode: codes[0], Precision: PrecisionCountry}
+	}
+	return placeLocation(place)
+}
+
+// placeLocation places an article at a gazetteer city or region
+func placeLocation(place Place) LocationData {
+	location := LocationData{
+		Country:     mapCountryCode(place.CountryCode),
+		CountryCode: place.CountryCode,
+		Lat:         place.Lat,
+		Lng:         place.Lng,
+		Precision:   PrecisionCity,
+	}
+	if place.Kind == PlaceKindRegion {
+		location.Region = place.Name
+		location.Precision = PrecisionRegion
+	} else {
+		location.City = place.Name
+	}
+	return location
+}
+
+// countryCodes resolves an article's country tags to distinct ISO codes, in order
+func (ls *LocationService) countryCodes(countries []string) []string {
+	var codes []string
+	seen := make(map[string]bool)
+	for _, country := range countries {
+		resolved, ok := ls.Gazetteer.Country(country)
+		if !ok || seen[resolved.Code] {
+			continue
+		}
+		seen[resolved.Code] = true
+		codes = append(codes, resolved.Code)
+	}
+	return codes
+}
+
+// locationPolicy reads LOCATION_POLICY (default most-specific)
+func locationPolicy() string {
+	switch policy := strings.ToLower(os.Getenv("LOCATION_POLICY")); policy {
+	case "":
+		return LocationPolicyMostSpecific
+	case LocationPolicyFirst, LocationPolicyAll, LocationPolicyMostSpecific:
+		return policy
+	default:
+		log.Printf("Unknown LOCATION_POLICY %q, using %s", policy, LocationPolicyMostSpecific)
+		return LocationPolicyMostSpecific
 	}
-	return "", "United States", nil
 }
 
+// mapCountryCode turns an ISO country code (or a country name) into the country's name
 func mapCountryCode(code string) string {
-	countryMap := map[string]string{
-		"us": "United States",
-		"cr": "Costa Rica",
-		"br": "Brazil",
-		"bo": "Bolivia",
-		"es": "Spain",
-		"gb": "United Kingdom",
-		"jp": "Japan",
-		"ca": "Canada",
-		"au": "Australia",
-		"de": "Germany",
-		"fr": "France",
-		"it": "Italy",
-		"mx": "Mexico",
-		"in": "India",
-		"cn": "China",
-		"ru": "Russia",
-		"kr": "South Korea",
-	}
-
-	if name, ok := countryMap[strings.ToLower(code)]; ok {
-		return name
+	if country, ok := DefaultGazetteer().Country(code); ok {
+		return country.Name
 	}
 	return code
 }
@@ -0,0 +1,94 @@
+package services
+
+import "testing"
+
+func TestProcessLocation(t *testing.T) {
+	tests := []struct {
+		name    string
+		article NewsArticle
+		policy  string
+		want    []LocationData
+	}{
+		{
+			"untagged names a city",
+			NewsArticle{Title: "Fire in Lisbon"},
+			LocationPolicyMostSpecific,
+			[]LocationData{{City: "Lisbon", Country: "Portugal", CountryCode: "pt", Precision: PrecisionCity}},
+		},
+		{
+			"tag that isn't a country, text names a city",
+			NewsArticle{Title: "Fire in Lisbon", Country: []string{"world"}},
+			LocationPolicyMostSpecific,
+			[]LocationData{{City: "Lisbon", Country: "Portugal", CountryCode: "pt", Precision: PrecisionCity}},
+		},
+		{
+			"untagged with an ambiguous name",
+			NewsArticle{Title: "Protests in Georgia"},
+			LocationPolicyMostSpecific,
+			[]LocationData{{Country: "United States", CountryCode: "us", Precision: PrecisionCountry}},
+		},
+		{
+			"untagged without a place",
+			NewsArticle{Title: "Markets rally on Monday"},
+			LocationPolicyMostSpecific,
+			[]LocationData{{Country: "United States", CountryCode: "us", Precision: PrecisionCountry}},
+		},
+		{
+			"tagged without a place",
+			NewsArticle{Title: "Markets rally on Monday", Country: []string{"portugal"}},
+			LocationPolicyMostSpecific,
+			[]LocationData{{Country: "Portugal", CountryCode: "pt", Precision: PrecisionCountry}},
+		},
+		{
+			"tagged names a region",
+			NewsArticle{Title: "Storm hits Georgia", Country: []string{"us"}},
+			LocationPolicyMostSpecific,
+			[]LocationData{{Region: "Georgia", Country: "United States", CountryCode: "us", Precision: PrecisionRegion}},
+		},
+		{
+			"most specific across countries",
+			NewsArticle{Title: "Fire in Lisbon", Country: []string{"es", "pt"}},
+			LocationPolicyMostSpecific,
+			[]LocationData{{City: "Lisbon", Country: "Portugal", CountryCode: "pt", Precision: PrecisionCity}},
+		},
+		{
+			"first country only",
+			NewsArticle{Title: "Fire in Lisbon", Country: []string{"es", "pt"}},
+			LocationPolicyFirst,
+			[]LocationData{{Country: "Spain", CountryCode: "es", Precision: PrecisionCountry}},
+		},
+		{
+			"one point per country",
+			NewsArticle{Title: "Fire in Lisbon", Country: []string{"es", "pt"}},
+			LocationPolicyAll,
+			[]LocationData{
+				{Country: "Spain", CountryCode: "es", Precision: PrecisionCountry},
+				{City: "Lisbon", Country: "Portugal", CountryCode: "pt", Precision: PrecisionCity},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ls := &LocationService{Gazetteer: DefaultGazetteer(), Policy: tt.policy}
+			locations, err := ls.ProcessLocation(tt.article)
+			if err != nil {
+				t.Fatalf("ProcessLocation: %v", err)
+			}
+			if len(locations) != len(tt.want) {
+				t.Fatalf("got %d locations %v, want %d", len(locations), locations, len(tt.want))
+			}
+			for i, location := range locations {
+				// only check coordinates were set for places, the tables own the values
+				want := tt.want[i]
+				if want.Precision != PrecisionCountry && location.Lat == 0 && location.Lng == 0 {
+					t.Errorf("location %d has no coordinates", i)
+				}
+				location.Lat, location.Lng = 0, 0
+				if location != want {
+					t.Errorf("location %d = %+v, want %+v", i, location, want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,139 @@
+package services
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// MemoryStore keeps events in memory. It backs FileStore and is useful on its
+// own in tests and when nothing should be written to disk.
+type MemoryStore struct {
+	Retention Retention
+
+	mu     sync.RWMutex
+	events []EmotionEvent // in ID order
+	byID   map[string]int // index into events
+	nextID uint64
+	onTrim func(dropped []EmotionEvent)
+}
+
+// NewMemoryStore creates an empty store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		byID:   make(map[string]int),
+		nextID: 1,
+	}
+}
+
+// Append assigns the event the next ID and records it
+func (ms *MemoryStore) Append(event EmotionEvent) (EmotionEvent, error) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	event.ID = strconv.FormatUint(ms.nextID, 10)
+	ms.add(event)
+	if now := time.Now(); ms.Retention.due(ms.events, now) {
+		ms.trim(now)
+	}
+	return event, nil
+}
+
+// add records an event that already has an ID; callers hold mu
+func (ms *MemoryStore) add(event EmotionEvent) {
+	if id, err := strconv.ParseUint(event.ID, 10, 64); err == nil && id >= ms.nextID {
+		ms.nextID = id + 1
+	}
+	ms.byID[event.ID] = len(ms.events)
+	ms.events = append(ms.events, event)
+}
+
+// trim drops the events outside the retention limits and returns how many it
+// dropped; callers hold mu
+func (ms *MemoryStore) trim(now time.Time) int {
+	n := ms.Retention.expired(ms.events, now)
+	if n == 0 {
+		return 0
+	}
+
+	if ms.onTrim != nil {
+		ms.onTrim(ms.events[:n])
+	}
+	for _, event := range ms.events[:n] {
+		delete(ms.byID, event.ID)
+	}
+	// copy so the dropped events can be freed
+	ms.events = slices.Clone(ms.events[n:])
+	for i, event := range ms.events {
+		ms.byID[event.ID] = i
+	}
+	return n
+}
+
+// OnTrim implements TrimNotifier
+func (ms *MemoryStore) OnTrim(fn func(dropped []EmotionEvent)) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	ms.onTrim = fn
+}
+
+func (ms *MemoryStore) Get(id string) (EmotionEvent, error) {
+	ms.mu.RLock()
+	defer ms.mu.RUnlock()
+
+	i, ok := ms.byID[id]
+	if !ok {
+		return EmotionEvent{}, ErrEventNotFound
+	}
+	return ms.events[i], nil
+}
+
+func (ms *MemoryStore) Recent(limit int, since time.Time) ([]EmotionEvent, error) {
+	ms.mu.RLock()
+	defer ms.mu.RUnlock()
+
+	start := len(ms.events)
+	for start > 0 && len(ms.events)-start < limit && !ms.events[start-1].Timestamp.Before(since) {
+		start--
+	}
+	return slices.Clone(ms.events[start:]), nil
+}
+
+func (ms *MemoryStore) Query(q EventQuery) (EventPage, error) {
+	ms.mu.RLock()
+	defer ms.mu.RUnlock()
+
+	start := 0
+	if q.After != "" {
+		after, err := strconv.ParseUint(q.After, 10, 64)
+		if err != nil {
+			return EventPage{}, fmt.Errorf("invalid cursor: %s", q.After)
+		}
+		// IDs are assigned in order, so events are sorted by ID
+		start, _ = slices.BinarySearchFunc(ms.events, after+1, func(event EmotionEvent, id uint64) int {
+			eventID, _ := strconv.ParseUint(event.ID, 10, 64)
+			return cmp.Compare(eventID, id)
+		})
+	}
+
+	limit := pageLimit(q.Limit)
+	page := EventPage{Events: []EmotionEvent{}}
+	for _, event := range ms.events[start:] {
+		if !q.Matches(event) {
+			continue
+		}
+		if len(page.Events) == limit {
+			page.Next = page.Events[limit-1].ID
+			break
+		}
+		page.Events = append(page.Events, event)
+	}
+	return page, nil
+}
+
+func (ms *MemoryStore) Close() error {
+	return nil
+}
@@ -2,21 +2,31 @@ package services
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"log"
 	"net/http"
 	"os"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
+
+	"emotisphere/websocket"
 )
 
 // represents a news article from newsdata.io
 type NewsArticle struct {
+	ArticleID   string   `json:"article_id"`
 	Title       string   `json:"title"`
+	Link        string   `json:"link"`
 	Description string   `json:"description"`
 	Content     string   `json:"content"`
 	Country     []string `json:"country"`
 	Language    string   `json:"language"`
 	PubDate     string   `json:"pubDate"`
+	Source      string   `json:"-"` // name of the NewsSource it came from, set by FetchAll
 }
 
 // represents the response from newsdata.io API
@@ -28,22 +38,62 @@ type NewsResponse struct {
 
 // NewsService handles fetching news data
 type NewsService struct {
-	APIKey string
-	Client *http.Client
+	APIKey     string
+	Client     *http.Client
+	BaseURL    string // newsdata.io API host
+	PageBudget int    // max pages (credits) spent per FetchNews call
+	Quota      *QuotaTracker
+	Retry      RetryPolicy
+
+	mu      sync.Mutex
+	lastRun map[string]time.Time // per country set
+	health  sourceHealth
 }
 
 // NewNewsService creates a new news service
 func NewNewsService() *NewsService {
+	baseURL := strings.TrimRight(os.Getenv("NEWSDATA_URL"), "/")
+	if baseURL == "" {
+		baseURL = "https://newsdata.io"
+	}
+
+	pageBudget := 3
+	if env := os.Getenv("NEWSDATA_PAGE_BUDGET"); env != "" {
+		if parsed, err := strconv.Atoi(env); err == nil && parsed > 0 {
+			pageBudget = parsed
+		}
+	}
+
+	dailyLimit := 200
+	if env := os.Getenv("NEWSDATA_DAILY_LIMIT"); env != "" {
+		if parsed, err := strconv.Atoi(env); err == nil && parsed >= 0 {
+			dailyLimit = parsed
+		}
+	}
+
 	return &NewsService{
 		APIKey: os.Getenv("NEWSDATA_API_KEY"),
 		Client: &http.Client{
 			Timeout: 30 * time.Second,
 		},
+		BaseURL:    baseURL,
+		PageBudget: pageBudget,
+		Quota:      NewQuotaTracker("newsdata", dailyLimit),
+		Retry:      NewRetryPolicy(),
+		lastRun:    make(map[string]time.Time),
 	}
 }
 
-// FetchNews fetches recent news articles
+// FetchNews fetches recent news articles, following nextPage until the page
+// budget is spent or it reaches articles older than the previous run
 func (ns *NewsService) FetchNews(countries []string) ([]NewsArticle, error) {
+	budget := ns.PageBudget
+	return ns.fetchNews(countries, &budget)
+}
+
+// fetchNews implements FetchNews, spending pages from a budget the caller may
+// share between several calls
+func (ns *NewsService) fetchNews(countries []string, budget *int) ([]NewsArticle, error) {
 	if ns.APIKey == "" {
 		return nil, fmt.Errorf("NEWSDATA_API_KEY not set in environment variables")
 	}
@@ -52,53 +102,276 @@ func (ns *NewsService) FetchNews(countries []string) ([]NewsArticle, error) {
 		countries = countries[:5]
 	}
 
-	url := fmt.Sprintf("https://newsdata.io/api/1/news?apikey=%s&language=en", ns.APIKey)
+	key := strings.Join(countries, ",")
+	ns.mu.Lock()
+	since := ns.lastRun[key]
+	ns.mu.Unlock()
+	runStart := time.Now()
 
-	if len(countries) > 0 {
-		countryStr := ""
-		for i, country := range countries {
-			if i > 0 {
-				countryStr += ","
+	var articles []NewsArticle
+	page := ""
+	pages := 0
+	complete := false
+	for *budget > 0 {
+		*budget--
+		newsResponse, err := ns.fetchPage(countries, page)
+		if err != nil {
+			if pages == 0 {
+				return nil, err
+			}
+			// keep what we already have
+			log.Printf("Stopping pagination after %d pages: %v", pages, err)
+			break
+		}
+		pages++
+
+		if newsResponse.Status != "success" {
+			// If status is not success, return empty results instead of error
+			// This allows processing to continue with other countries
+			break
+		}
+
+		// older articles are still kept, newsdata.io sometimes indexes them late;
+		// the dedup store drops the ones already processed
+		reachedOld := false
+		for _, article := range newsResponse.Results {
+			if published, ok := parseNewsDate(article.PubDate); ok && !since.IsZero() && published.Before(since) {
+				reachedOld = true
 			}
-			countryStr += country
+			articles = append(articles, article)
 		}
-		url += fmt.Sprintf("&country=%s", countryStr)
+
+		if reachedOld || newsResponse.NextPage == "" {
+			complete = true
+			break
+		}
+		page = newsResponse.NextPage
+	}
+
+	// only move past this run once everything newer than the previous one was
+	// read, so pages left unread after an error or with the budget spent are
+	// fetched next time
+	if complete {
+		ns.mu.Lock()
+		ns.lastRun[key] = runStart
+		ns.mu.Unlock()
+	}
+
+	if articles == nil {
+		articles = []NewsArticle{}
+	}
+	return articles, nil
+}
+
+// fetchPage fetches a single page of results; page is the nextPage token of
+// the previous response, or empty for the first page
+func (ns *NewsService) fetchPage(countries []string, page string) (*NewsResponse, error) {
+	url := fmt.Sprintf("%s/api/1/news?apikey=%s&language=en", ns.BaseURL, ns.APIKey)
+
+	if len(countries) > 0 {
+		url += fmt.Sprintf("&country=%s", strings.Join(countries, ","))
 	}
 
 	// Add category for better results (optional, helps with free tier)
 	// Using "top" category which is available in free tier
 	url += "&category=top"
 
+	if page != "" {
+		url += "&page=" + page
+	}
+
+	var body []byte
+	err := ns.Retry.Do(func() error {
+		var err error
+		body, err = ns.fetchOnce(url)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var newsResponse NewsResponse
+	if err := json.Unmarshal(body, &newsResponse); err != nil {
+		return nil, fmt.Errorf("failed to parse news response: %w", err)
+	}
+
+	return &newsResponse, nil
+}
+
+// fetchOnce makes a single request, spending one credit
+func (ns *NewsService) fetchOnce(url string) ([]byte, error) {
+	if err := ns.Quota.Acquire(); err != nil {
+		return nil, err
+	}
+
 	resp, err := ns.Client.Get(url)
 	if err != nil {
-		return nil, fmt.Errorf("failed to fetch news: %w", err)
+		return nil, networkError("news", err)
 	}
 	defer resp.Body.Close()
 
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read response body: %w", err)
+		return nil, networkError("news", err)
+	}
+
+	if resp.StatusCode == http.StatusTooManyRequests {
+		if wait, ok := parseRetryAfter(resp.Header); ok {
+			ns.Quota.PauseUntil(time.Now().Add(wait))
+		} else {
+			ns.Quota.Exhaust()
+		}
+		// the quota tracker pauses the scheduler, retrying now would just burn credits
+		apiErr := newAPIError("news", resp, body)
+		apiErr.Retryable = false
+		return nil, fmt.Errorf("%w: %w", ErrQuotaExceeded, apiErr)
+	}
+
+	if newsErr, ok := parseNewsError(body); ok {
+		apiErr := newAPIError("news", resp, body)
+		if isRateLimitCode(newsErr.Code) {
+			ns.Quota.Exhaust()
+			apiErr.Retryable = false
+			return nil, fmt.Errorf("%w: %w", ErrQuotaExceeded, apiErr)
+		}
+		apiErr.Body = fmt.Sprintf("%s (%s)", newsErr.Message, newsErr.Code)
+		return nil, apiErr
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("news API returned status %d: %s", resp.StatusCode, string(body))
+		return nil, newAPIError("news", resp, body)
 	}
 
-	var newsResponse NewsResponse
-	if err := json.Unmarshal(body, &newsResponse); err != nil {
-		return nil, fmt.Errorf("failed to parse news response: %w", err)
+	return body, nil
+}
+
+// newsError is the results object of an error response from newsdata.io
+type newsError struct {
+	Message string `json:"message"`
+	Code    string `json:"code"`
+}
+
+// parseNewsError extracts the error from a {"status":"error"} response body
+func parseNewsError(body []byte) (newsError, bool) {
+	var response struct {
+		Status  string          `json:"status"`
+		Results json.RawMessage `json:"results"`
+	}
+	if err := json.Unmarshal(body, &response); err != nil || response.Status != "error" {
+		return newsError{}, false
 	}
 
-	if newsResponse.Status != "success" {
-		// If status is not success, return empty results instead of error
-		// This allows processing to continue with other countries
-		return []NewsArticle{}, nil
+	var apiErr newsError
+	if err := json.Unmarshal(response.Results, &apiErr); err != nil {
+		apiErr.Message = string(response.Results)
 	}
+	return apiErr, true
+}
+
+func isRateLimitCode(code string) bool {
+	code = strings.ToLower(code)
+	return strings.Contains(code, "ratelimit") || strings.Contains(code, "limitexceeded") || strings.Contains(code, "quota")
+}
 
-	return newsResponse.Results, nil
+// parseNewsDate parses a newsdata.io pubDate (UTC)
+func parseNewsDate(value string) (time.Time, bool) {
+	parsed, err := time.Parse(newsDateLayout, value)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return parsed, true
+}
+
+// Name implements NewsSource
+func (ns *NewsService) Name() string {
+	return "newsdata"
+}
+
+// FetchArticles implements NewsSource. If the combined request is rejected it
+// falls back to one request per country so a single bad country doesn't lose the
+// batch. Outages, rate limits and quota errors are returned as they are: every
+// fallback request would fail the same way and burn more credits, so the next
+// tick tries again instead. The combined request and the fallback share one page budget.
+func (ns *NewsService) FetchArticles(regions []string) ([]NewsArticle, error) {
+	budget := ns.PageBudget
+	articles, err := ns.fetchNews(regions, &budget)
+	if err != nil && (len(regions) < 2 || !isCountryError(err)) {
+		ns.health.record(ns.Name(), 0, err)
+		return nil, err
+	}
+	if err != nil {
+		log.Printf("Error fetching news: %v", err)
+		for _, country := range regions {
+			if budget <= 0 {
+				log.Printf("Page budget spent, skipping the remaining countries")
+				break
+			}
+			countryArticles, countryErr := ns.fetchNews([]string{country}, &budget)
+			if countryErr != nil && !isCountryError(countryErr) {
+				log.Printf("Stopping per-country fallback: %v", countryErr)
+				break
+			}
+			if countryErr == nil && len(countryArticles) > 0 {
+				log.Printf("Successfully fetched %d articles for %s", len(countryArticles), country)
+				articles = append(articles, countryArticles...)
+			} else {
+				log.Printf("Could not fetch articles for %s: %v", country, countryErr)
+			}
+		}
+
+		if len(articles) == 0 {
+			ns.health.record(ns.Name(), 0, err)
+			return nil, err
+		}
+	}
+
+	ns.health.record(ns.Name(), len(articles), nil)
+	return articles, nil
+}
+
+// isCountryError reports whether err is a permanent rejection of the request
+// that one of its countries may have caused. A bad key (401/403) fails every
+// request, so it doesn't count.
+func isCountryError(err error) bool {
+	var apiErr *APIError
+	if errors.Is(err, ErrQuotaExceeded) || !errors.As(err, &apiErr) || apiErr.Retryable {
+		return false
+	}
+	if hasStatus(err, http.StatusUnauthorized, http.StatusForbidden) {
+		return false
+	}
+	return apiErr.StatusCode >= 400 && apiErr.StatusCode < 500
+}
+
+// Status implements NewsSource
+func (ns *NewsService) Status() SourceStatus {
+	status := ns.health.snapshot(ns.Name())
+	quota := ns.Quota.Status()
+	status.Remaining = quota.Remaining
+	status.PausedUntil = quota.PausedUntil
+	if ns.APIKey == "" {
+		status.Healthy = false
+		status.LastError = "NEWSDATA_API_KEY not set"
+	}
+	return status
+}
+
+// Configured implements NewsSource
+func (ns *NewsService) Configured() bool {
+	return ns.APIKey != ""
+}
+
+// QuotaStatus implements QuotaReporter
+func (ns *NewsService) QuotaStatus() websocket.QuotaData {
+	return ns.Quota.Status()
 }
 
 func (ns *NewsService) ExtractText(article NewsArticle) string {
+	return ExtractText(article)
+}
+
+// ExtractText returns the best text available for analysis
+func ExtractText(article NewsArticle) string {
 	// Prefer content, then description, then title
 	if article.Content != "" {
 		return article.Content
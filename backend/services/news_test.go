@@ -0,0 +1,342 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// newsServer is a fake newsdata.io that serves responses from handle and
+// records the query of every request
+type newsServer struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	requests []map[string]string
+}
+
+func newNewsServer(t *testing.T, handle func(query map[string]string) (int, any)) *newsServer {
+	t.Helper()
+
+	ns := &newsServer{}
+	ns.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/1/news" {
+			http.NotFound(w, r)
+			return
+		}
+
+		query := make(map[string]string)
+		for key := range r.URL.Query() {
+			query[key] = r.URL.Query().Get(key)
+		}
+		ns.mu.Lock()
+		ns.requests = append(ns.requests, query)
+		ns.mu.Unlock()
+
+		status, body := handle(query)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(body)
+	}))
+	t.Cleanup(ns.Close)
+	return ns
+}
+
+func (ns *newsServer) requestCount() int {
+	ns.mu.Lock()
+	defer ns.mu.Unlock()
+	return len(ns.requests)
+}
+
+// newTestNewsService points a NewsService at server with an unlimited quota
+// and no retry delays
+func newTestNewsService(server *newsServer, pageBudget int) *NewsService {
+	return &NewsService{
+		APIKey:     "test",
+		Client:     server.Client(),
+		BaseURL:    server.URL,
+		PageBudget: pageBudget,
+		Quota:      NewQuotaTracker("newsdata", 0),
+		Retry:      RetryPolicy{MaxAttempts: 3},
+		lastRun:    make(map[string]time.Time),
+	}
+}
+
+func testArticle(id string, published time.Time) NewsArticle {
+	return NewsArticle{ArticleID: id, Title: id, PubDate: published.UTC().Format(newsDateLayout)}
+}
+
+func TestFetchNewsSpendsPageBudget(t *testing.T) {
+	now := time.Now()
+	server := newNewsServer(t, func(query map[string]string) (int, any) {
+		page := query["page"]
+		return http.StatusOK, NewsResponse{
+			Status:   "success",
+			Results:  []NewsArticle{testArticle("after-"+page, now)},
+			NextPage: page + "x", // always another page
+		}
+	})
+	ns := newTestNewsService(server, 3)
+
+	articles, err := ns.FetchNews([]string{"us"})
+	if err != nil {
+		t.Fatalf("FetchNews: %v", err)
+	}
+	if got := server.requestCount(); got != 3 {
+		t.Errorf("made %d requests, want the page budget of 3", got)
+	}
+	if len(articles) != 3 {
+		t.Errorf("got %d articles, want 3", len(articles))
+	}
+	if used := ns.Quota.Status().Used; used != 3 {
+		t.Errorf("spent %d credits, want 3", used)
+	}
+}
+
+func TestFetchNewsFollowsNextPage(t *testing.T) {
+	now := time.Now()
+	server := newNewsServer(t, func(query map[string]string) (int, any) {
+		switch query["page"] {
+		case "":
+			return http.StatusOK, NewsResponse{Status: "success", Results: []NewsArticle{testArticle("a", now)}, NextPage: "p2"}
+		case "p2":
+			return http.StatusOK, NewsResponse{Status: "success", Results: []NewsArticle{testArticle("b", now)}}
+		}
+		return http.StatusBadRequest, map[string]string{"status": "error"}
+	})
+	ns := newTestNewsService(server, 10)
+
+	articles, err := ns.FetchNews([]string{"us", "gb"})
+	if err != nil {
+		t.Fatalf("FetchNews: %v", err)
+	}
+	if len(articles) != 2 || articles[0].ArticleID != "a" || articles[1].ArticleID != "b" {
+		t.Fatalf("got %+v, want articles a and b", articles)
+	}
+	if got := server.requestCount(); got != 2 {
+		t.Errorf("made %d requests, want 2 (stop at the last page)", got)
+	}
+
+	first := server.requests[0]
+	if first["apikey"] != "test" || first["country"] != "us,gb" || first["language"] != "en" {
+		t.Errorf("unexpected first request %v", first)
+	}
+}
+
+func TestFetchNewsStopsAtOldArticles(t *testing.T) {
+	lastRun := time.Now().Add(-time.Hour)
+	server := newNewsServer(t, func(query map[string]string) (int, any) {
+		return http.StatusOK, NewsResponse{
+			Status: "success",
+			Results: []NewsArticle{
+				testArticle("new", lastRun.Add(30*time.Minute)),
+				testArticle("old", lastRun.Add(-30*time.Minute)),
+			},
+			NextPage: "more",
+		}
+	})
+	ns := newTestNewsService(server, 5)
+	ns.lastRun["us"] = lastRun
+
+	articles, err := ns.FetchNews([]string{"us"})
+	if err != nil {
+		t.Fatalf("FetchNews: %v", err)
+	}
+	// the late-indexed old article is kept, it only ends pagination
+	if len(articles) != 2 {
+		t.Errorf("got %d articles, want both from the first page", len(articles))
+	}
+	if got := server.requestCount(); got != 1 {
+		t.Errorf("made %d requests, want 1 (stop once older articles show up)", got)
+	}
+	if !ns.lastRun["us"].After(lastRun) {
+		t.Error("last run time was not advanced")
+	}
+}
+
+func TestFetchNewsKeepsPagesBeforeAnError(t *testing.T) {
+	now := time.Now()
+	server := newNewsServer(t, func(query map[string]string) (int, any) {
+		if query["page"] == "" {
+			return http.StatusOK, NewsResponse{Status: "success", Results: []NewsArticle{testArticle("a", now)}, NextPage: "p2"}
+		}
+		return http.StatusUnprocessableEntity, map[string]any{
+			"status":  "error",
+			"results": map[string]string{"message": "bad page", "code": "UnsupportedFilter"},
+		}
+	})
+	ns := newTestNewsService(server, 5)
+
+	articles, err := ns.FetchNews([]string{"us"})
+	if err != nil {
+		t.Fatalf("FetchNews: %v", err)
+	}
+	if len(articles) != 1 {
+		t.Errorf("got %d articles, want the 1 from the first page", len(articles))
+	}
+	if _, ok := ns.lastRun["us"]; ok {
+		t.Error("last run advanced although the second page was never read")
+	}
+}
+
+func TestFetchNewsKeepsWatermarkWhenBudgetRunsOut(t *testing.T) {
+	now := time.Now()
+	server := newNewsServer(t, func(query map[string]string) (int, any) {
+		return http.StatusOK, NewsResponse{Status: "success", Results: []NewsArticle{testArticle("a"+query["page"], now)}, NextPage: query["page"] + "x"}
+	})
+	ns := newTestNewsService(server, 2)
+
+	if _, err := ns.FetchNews([]string{"us"}); err != nil {
+		t.Fatalf("FetchNews: %v", err)
+	}
+	if _, ok := ns.lastRun["us"]; ok {
+		t.Error("last run advanced although pages were left unread")
+	}
+}
+
+func TestFetchNewsErrorBody(t *testing.T) {
+	server := newNewsServer(t, func(query map[string]string) (int, any) {
+		return http.StatusUnauthorized, map[string]any{
+			"status":  "error",
+			"results": map[string]string{"message": "API key invalid", "code": "Unauthorized"},
+		}
+	})
+	ns := newTestNewsService(server, 3)
+
+	_, err := ns.FetchNews([]string{"us"})
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	if IsRetryable(err) {
+		t.Errorf("a bad key should not be retryable: %v", err)
+	}
+	if want := fmt.Sprintf("news API returned status %d: API key invalid (Unauthorized)", http.StatusUnauthorized); err.Error() != want {
+		t.Errorf("error = %q, want %q", err, want)
+	}
+	if got := server.requestCount(); got != 1 {
+		t.Errorf("made %d requests, want 1 (no retries for a permanent error)", got)
+	}
+}
+
+func TestFetchNewsRateLimited(t *testing.T) {
+	server := newNewsServer(t, func(query map[string]string) (int, any) {
+		return http.StatusTooManyRequests, map[string]string{"status": "error"}
+	})
+	ns := newTestNewsService(server, 3)
+
+	_, err := ns.FetchNews([]string{"us"})
+	if !errors.Is(err, ErrQuotaExceeded) {
+		t.Fatalf("error = %v, want ErrQuotaExceeded", err)
+	}
+	if got := server.requestCount(); got != 1 {
+		t.Errorf("made %d requests, want 1 (no retries on a rate limit)", got)
+	}
+	if ns.Quota.PausedUntil().IsZero() {
+		t.Error("quota was not paused")
+	}
+
+	// paused: the next call doesn't reach the server
+	if _, err := ns.FetchNews([]string{"us"}); !errors.Is(err, ErrQuotaExceeded) {
+		t.Errorf("error = %v, want ErrQuotaExceeded while paused", err)
+	}
+	if got := server.requestCount(); got != 1 {
+		t.Errorf("made %d requests while paused, want none", got)
+	}
+}
+
+func TestFetchArticlesNoFallbackOnOutage(t *testing.T) {
+	server := newNewsServer(t, func(query map[string]string) (int, any) {
+		return http.StatusServiceUnavailable, map[string]string{"status": "error"}
+	})
+	ns := newTestNewsService(server, 3)
+
+	_, err := ns.FetchArticles([]string{"us", "gb", "fr", "de", "in"})
+	if !IsRetryable(err) {
+		t.Fatalf("error = %v, want the retryable outage error", err)
+	}
+	// one FetchNews with its retries, no per-country requests
+	if got := server.requestCount(); got != ns.Retry.MaxAttempts {
+		t.Errorf("made %d requests, want %d", got, ns.Retry.MaxAttempts)
+	}
+	if used := ns.Quota.Status().Used; used != ns.Retry.MaxAttempts {
+		t.Errorf("spent %d credits, want %d", used, ns.Retry.MaxAttempts)
+	}
+}
+
+func TestFetchArticlesFallsBackPerCountry(t *testing.T) {
+	now := time.Now()
+	server := newNewsServer(t, func(query map[string]string) (int, any) {
+		if strings.Contains(query["country"], "xx") {
+			return http.StatusUnprocessableEntity, map[string]any{
+				"status":  "error",
+				"results": map[string]string{"message": "invalid country", "code": "UnsupportedFilter"},
+			}
+		}
+		return http.StatusOK, NewsResponse{Status: "success", Results: []NewsArticle{testArticle(query["country"], now)}}
+	})
+	ns := newTestNewsService(server, 4)
+
+	articles, err := ns.FetchArticles([]string{"us", "xx", "gb"})
+	if err != nil {
+		t.Fatalf("FetchArticles: %v", err)
+	}
+	if len(articles) != 2 || articles[0].ArticleID != "us" || articles[1].ArticleID != "gb" {
+		t.Errorf("got %+v, want the articles for us and gb", articles)
+	}
+	// the combined request, then one per country
+	if got := server.requestCount(); got != 4 {
+		t.Errorf("made %d requests, want 4", got)
+	}
+}
+
+func TestFetchArticlesNoFallbackOnBadKey(t *testing.T) {
+	server := newNewsServer(t, func(query map[string]string) (int, any) {
+		return http.StatusUnauthorized, map[string]any{
+			"status":  "error",
+			"results": map[string]string{"message": "API key invalid", "code": "Unauthorized"},
+		}
+	})
+	ns := newTestNewsService(server, 3)
+
+	if _, err := ns.FetchArticles([]string{"us", "gb"}); err == nil {
+		t.Fatal("expected an error")
+	}
+	if got := server.requestCount(); got != 1 {
+		t.Errorf("made %d requests, want 1", got)
+	}
+	if ns.Status().Healthy {
+		t.Error("source reported healthy after a failed fetch")
+	}
+}
+
+func TestFetchArticlesFallbackSharesPageBudget(t *testing.T) {
+	now := time.Now()
+	server := newNewsServer(t, func(query map[string]string) (int, any) {
+		if strings.Contains(query["country"], ",") {
+			return http.StatusUnprocessableEntity, map[string]any{
+				"status":  "error",
+				"results": map[string]string{"message": "invalid country", "code": "UnsupportedFilter"},
+			}
+		}
+		return http.StatusOK, NewsResponse{Status: "success", Results: []NewsArticle{testArticle(query["country"], now)}}
+	})
+	ns := newTestNewsService(server, 3)
+
+	articles, err := ns.FetchArticles([]string{"us", "gb", "fr", "de", "in"})
+	if err != nil {
+		t.Fatalf("FetchArticles: %v", err)
+	}
+	// the combined request, then countries until the budget of 3 is spent
+	if got := server.requestCount(); got != 3 {
+		t.Errorf("made %d requests, want 3", got)
+	}
+	if len(articles) != 2 {
+		t.Errorf("got %d articles, want 2", len(articles))
+	}
+}
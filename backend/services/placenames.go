@@ -0,0 +1,153 @@
+package services
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Mentions in the title say more about where a story happens than mentions
+// deep in the body, so they count more
+const (
+	titleMentionWeight       = 3
+	descriptionMentionWeight = 2
+	contentMentionWeight     = 1
+)
+
+// placeMention tallies how often a place was mentioned in an article
+type placeMention struct {
+	place *Place
+	score int
+	first int // order of the first mention, to break ties
+}
+
+// ExtractPlace finds the city or region an article is most about by scanning
+// its text for gazetteer names. Names are only matched when capitalized, so
+// "nice weather" is not Nice. When countryCodes is non-empty only places in
+// those countries count, which is how "Georgia" in a US article becomes the
+// state; without countries only names that belong to a single country count.
+func (g *Gazetteer) ExtractPlace(article NewsArticle, countryCodes []string) (Place, bool) {
+	allowed := make(map[string]bool, len(countryCodes))
+	for _, code := range countryCodes {
+		allowed[strings.ToLower(code)] = true
+	}
+
+	mentions := make(map[*Place]*placeMention)
+	order := 0
+	scan := func(text string, weight int) {
+		for _, place := range g.scanPlaces(text, allowed) {
+			mention, ok := mentions[place]
+			if !ok {
+				mention = &placeMention{place: place, first: order}
+				mentions[place] = mention
+				order++
+			}
+			mention.score += weight
+		}
+	}
+	scan(article.Title, titleMentionWeight)
+	scan(article.Description, descriptionMentionWeight)
+	scan(article.Content, contentMentionWeight)
+
+	var best *placeMention
+	for _, mention := range mentions {
+		if best == nil || mention.outranks(best) {
+			best = mention
+		}
+	}
+	if best == nil {
+		return Place{}, false
+	}
+	return *best.place, true
+}
+
+// outranks prefers more mentions, then cities over regions, then bigger cities,
+// then whichever was mentioned first
+func (m *placeMention) outranks(other *placeMention) bool {
+	if m.score != other.score {
+		return m.score > other.score
+	}
+	if m.place.Kind != other.place.Kind {
+		return m.place.Kind == PlaceKindCity
+	}
+	if m.place.Population != other.place.Population {
+		return m.place.Population > other.place.Population
+	}
+	return m.first < other.first
+}
+
+// scanPlaces returns a place for every name mentioned in text, preferring the
+// longest name at each position ("New York" over "York")
+func (g *Gazetteer) scanPlaces(text string, allowed map[string]bool) []*Place {
+	words := placeWords(text)
+
+	var found []*Place
+	for i := 0; i < len(words); i++ {
+		if !startsUpper(words[i]) {
+			continue
+		}
+		for n := min(g.maxWords, len(words)-i); n > 0; n-- {
+			place := g.resolveMention(strings.Join(words[i:i+n], " "), allowed)
+			if place != nil {
+				found = append(found, place)
+				i += n - 1
+				break
+			}
+		}
+	}
+	return found
+}
+
+// resolveMention picks the place a name refers to, or nil when the name is
+// unknown, outside the allowed countries or ambiguous
+func (g *Gazetteer) resolveMention(name string, allowed map[string]bool) *Place {
+	key := normalizeQuery(name)
+	candidates := g.places[key]
+	if len(candidates) == 0 {
+		return nil
+	}
+
+	if len(allowed) > 0 {
+		for _, place := range candidates {
+			if allowed[place.CountryCode] {
+				return place
+			}
+		}
+		return nil
+	}
+
+	// a name shared with another country ("Georgia") is ambiguous too
+	if country, ok := g.countryNames[key]; ok && country.Code != candidates[0].CountryCode {
+		return nil
+	}
+	for _, place := range candidates[1:] {
+		if place.CountryCode != candidates[0].CountryCode {
+			return nil
+		}
+	}
+	return candidates[0]
+}
+
+// placeWords splits text into words, dropping possessives and trailing dots
+// so "Kyiv's" and "Paris." match their places
+func placeWords(text string) []string {
+	fields := strings.FieldsFunc(text, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '-' && r != '\'' && r != '’' && r != '.'
+	})
+
+	words := make([]string, 0, len(fields))
+	for _, field := range fields {
+		field = strings.TrimSuffix(field, "'s")
+		field = strings.TrimSuffix(field, "’s")
+		field = strings.Trim(field, ".-'’")
+		if field != "" {
+			words = append(words, field)
+		}
+	}
+	return words
+}
+
+func startsUpper(word string) bool {
+	r, _ := utf8.DecodeRuneInString(word)
+	return unicode.IsUpper(r)
+}
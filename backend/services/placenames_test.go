@@ -0,0 +1,111 @@
+package services
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestExtractPlace(t *testing.T) {
+	tests := []struct {
+		name      string
+		article   NewsArticle
+		countries []string
+		want      string // place name, empty when nothing is found
+		wantCode  string
+	}{
+		// disambiguation by the article's countries
+		{"Georgia in a US article", NewsArticle{Title: "Storm hits Georgia"}, []string{"us"}, "Georgia", "us"},
+		{"Georgia in a Georgian article", NewsArticle{Title: "Protests in Georgia"}, []string{"ge"}, "", ""},
+		{"Georgia untagged", NewsArticle{Title: "Protests in Georgia"}, nil, "", ""},
+		{"outside the countries", NewsArticle{Title: "Fire in Lisbon"}, []string{"es"}, "", ""},
+
+		// longest match
+		{"New York", NewsArticle{Title: "Rally in New York"}, nil, "New York", "us"},
+		{"New York State", NewsArticle{Title: "Floods across New York State"}, nil, "New York State", "us"},
+
+		// possessives and punctuation
+		{"possessive", NewsArticle{Title: "Kyiv's mayor speaks"}, nil, "Kyiv", "ua"},
+		{"typographic possessive", NewsArticle{Title: "Kyiv’s mayor speaks"}, nil, "Kyiv", "ua"},
+		{"trailing dot", NewsArticle{Title: "Crowds gather. Later, Lisbon."}, nil, "Lisbon", "pt"},
+		{"alias", NewsArticle{Title: "Shelling near Kiev"}, nil, "Kyiv", "ua"},
+
+		// capitalization gating
+		{"lowercase", NewsArticle{Title: "Have a nice weekend"}, nil, "", ""},
+		{"capitalized", NewsArticle{Title: "Festival opens in Nice"}, nil, "Nice", "fr"},
+
+		// untagged text
+		{"untagged", NewsArticle{Title: "Fire in Lisbon"}, nil, "Lisbon", "pt"},
+		{"no place", NewsArticle{Title: "Markets rally on Monday"}, nil, "", ""},
+
+		// ranking
+		{"title outweighs content", NewsArticle{Title: "Fire in Lisbon", Content: "Firefighters from Nice helped"}, nil, "Lisbon", "pt"},
+		{"mentions add up", NewsArticle{Title: "Fire in Lisbon", Description: "Nice", Content: "Nice and Nice"}, nil, "Nice", "fr"},
+		{"bigger city on a tie", NewsArticle{Title: "Tbilisi and Kyiv sign deal"}, nil, "Kyiv", "ua"},
+		{"city over region on a tie", NewsArticle{Title: "Georgia and Tbilisi"}, []string{"us", "ge"}, "Tbilisi", "ge"},
+	}
+
+	g := DefaultGazetteer()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			place, ok := g.ExtractPlace(tt.article, tt.countries)
+			if tt.want == "" {
+				if ok {
+					t.Errorf("ExtractPlace found %s (%s), want nothing", place.Name, place.CountryCode)
+				}
+				return
+			}
+			if !ok {
+				t.Fatalf("ExtractPlace found nothing, want %s", tt.want)
+			}
+			if place.Name != tt.want || place.CountryCode != tt.wantCode {
+				t.Errorf("ExtractPlace = %s (%s), want %s (%s)", place.Name, place.CountryCode, tt.want, tt.wantCode)
+			}
+		})
+	}
+}
+
+func TestScanPlacesPrefersLongestName(t *testing.T) {
+	g := &Gazetteer{
+		countries:    make(map[string]*Country),
+		countryNames: make(map[string]*Country),
+		places:       make(map[string][]*Place),
+	}
+	york := &Place{Name: "York", Kind: PlaceKindCity, CountryCode: "gb"}
+	newYork := &Place{Name: "New York", Kind: PlaceKindCity, CountryCode: "us"}
+	g.addPlace("York", york)
+	g.addPlace("New York", newYork)
+
+	tests := []struct {
+		text string
+		want []*Place
+	}{
+		{"Flights from New York", []*Place{newYork}},
+		{"Flights from York", []*Place{york}},
+		{"York and New York", []*Place{york, newYork}},
+		{"new York", []*Place{york}}, // only capitalized words start a name
+	}
+
+	for _, tt := range tests {
+		if got := g.scanPlaces(tt.text, nil); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("scanPlaces(%q) = %v, want %v", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestPlaceWords(t *testing.T) {
+	tests := []struct {
+		text string
+		want []string
+	}{
+		{"Kyiv's mayor", []string{"Kyiv", "mayor"}},
+		{"Kyiv’s mayor", []string{"Kyiv", "mayor"}},
+		{"Washington, D.C.", []string{"Washington", "D.C"}},
+		{"Guinea-Bissau's capital", []string{"Guinea-Bissau", "capital"}},
+	}
+
+	for _, tt := range tests {
+		if got := placeWords(tt.text); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("placeWords(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
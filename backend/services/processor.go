@@ -1,7 +1,12 @@
 package services
 
 import (
+	"crypto/sha1"
+	"encoding/hex"
 	"log"
+	"os"
+	"strconv"
+	"strings"
 	"time"
 
 	"emotisphere/websocket"
@@ -9,9 +14,18 @@ import (
 
 // Processor handles the emotion analysis pipeline
 type Processor struct {
-	NewsService     *NewsService
-	EmotionService  *EmotionService
+	Sources         []NewsSource
+	Classifier      EmotionClassifier
+	BatchSize       int // articles per ClassifyBatch call
 	LocationService *LocationService
+	Geocoder        Geocoder
+	SplitWeight     bool    // weight multi-country articles 1/N per point
+	Jitter          *Jitter // spreads points that would otherwise stack
+	Dedup           *DedupStore
+	Store           Store         // records every published event, nil disables recording
+	Rollup          *Rollup       // per-country aggregates of the published events still retained
+	SnapshotSize    int           // events replayed to new clients, 0 disables the snapshot
+	SnapshotWindow  time.Duration // only replay events this recent, 0 means no limit
 	Hub             *websocket.Hub
 	Running         bool
 	StopChan        chan bool
@@ -19,10 +33,27 @@ type Processor struct {
 
 // NewProcessor creates a new processor
 func NewProcessor(hub *websocket.Hub) *Processor {
+	locationService := NewLocationService()
+	store := NewStoreFromEnv()
+	rollup := NewRollupFromStore(store)
+	if store == nil {
+		// no store to follow, so the rollup bounds itself
+		rollup.MaxAge = RetentionFromEnv().MaxAge
+	}
+
 	return &Processor{
-		NewsService:     NewNewsService(),
-		EmotionService:  NewEmotionService(),
-		LocationService: NewLocationService(),
+		Sources:         NewNewsSources(),
+		Classifier:      NewEmotionClassifierFromEnv(),
+		BatchSize:       emotionBatchSize(),
+		LocationService: locationService,
+		Geocoder:        NewGeocoderFromEnv(locationService),
+		SplitWeight:     splitWeight(),
+		Jitter:          NewJitterFromEnv(),
+		Dedup:           NewDedupStore(),
+		Store:           store,
+		Rollup:          rollup,
+		SnapshotSize:    snapshotSize(),
+		SnapshotWindow:  snapshotWindow(),
 		Hub:             hub,
 		StopChan:        make(chan bool),
 	}
@@ -61,84 +92,282 @@ func (p *Processor) Stop() {
 }
 
 func (p *Processor) ProcessBatch(countries []string) {
-	log.Printf("Fetching news articles for countries: %v", countries)
+	p.Dedup.Prune()
+	defer p.broadcastQuotas()
+
+	sources := p.activeSources()
+	if len(sources) == 0 {
+		log.Printf("All news sources are paused, skipping batch")
+		return
+	}
 
-	articles, err := p.NewsService.FetchNews(countries)
+	log.Printf("Fetching news articles for countries: %v", countries)
+	articles, err := FetchAll(sources, countries)
 	if err != nil {
-		log.Printf("Error fetching news: %v", err)
-		// Don't broadcast error, just log it - try processing individual countries
-		// Try fetching for each country individually as fallback
-		for _, country := range countries {
-			countryArticles, countryErr := p.NewsService.FetchNews([]string{country})
-			if countryErr == nil && len(countryArticles) > 0 {
-				log.Printf("Successfully fetched %d articles for %s", len(countryArticles), country)
-				articles = append(articles, countryArticles...)
-			} else {
-				log.Printf("Could not fetch articles for %s: %v", country, countryErr)
+		// Don't broadcast error, just log it
+		log.Printf("No articles fetched: %v", err)
+		return
+	}
+
+	log.Printf("Fetched %d articles total, processing...", len(articles))
+
+	// skip empty and already processed articles before spending any inference
+	var pending []NewsArticle
+	var texts []string
+	for _, article := range articles {
+		text := ExtractText(article)
+		if text == "" || p.Dedup.CheckAndMark(article) {
+			continue
+		}
+		pending = append(pending, article)
+		texts = append(texts, text)
+	}
+
+	batchSize := max(p.BatchSize, 1)
+	for start := 0; start < len(pending); start += batchSize {
+		end := min(start+batchSize, len(pending))
+
+		results, err := p.Classifier.ClassifyBatch(texts[start:end])
+		if err != nil {
+			log.Printf("Error analyzing batch of %d articles: %v", end-start, err)
+			for _, article := range pending[start:end] {
+				// let the next batch retry them
+				p.Dedup.Forget(article)
 			}
+			continue
 		}
 
-		if len(articles) == 0 {
-			log.Printf("No articles fetched for any country")
-			return
+		for i, result := range results {
+			p.publish(pending[start+i], texts[start+i], result)
 		}
 	}
+}
 
-	log.Printf("Fetched %d articles total, processing...", len(articles))
+// activeSources returns the sources that are not paused by their quota
+func (p *Processor) activeSources() []NewsSource {
+	var active []NewsSource
+	now := time.Now()
+	for _, source := range p.Sources {
+		if paused := source.Status().PausedUntil; now.Before(paused) {
+			log.Printf("Source %s paused until %s", source.Name(), paused.Format(time.RFC3339))
+			continue
+		}
+		active = append(active, source)
+	}
+	return active
+}
 
-	for i, article := range articles {
-		go func(art NewsArticle, index int) {
-			if index > 0 {
-				time.Sleep(time.Second * 2) // 2 second delay between requests
-			}
-			p.ProcessArticle(art)
-		}(article, i)
+// Quotas reports the remaining budget of every source that has one
+func (p *Processor) Quotas() []websocket.QuotaData {
+	quotas := []websocket.QuotaData{}
+	for _, source := range p.Sources {
+		if reporter, ok := source.(QuotaReporter); ok {
+			quotas = append(quotas, reporter.QuotaStatus())
+		}
+	}
+	return quotas
+}
+
+// broadcastQuotas sends the remaining budgets to clients as info messages
+func (p *Processor) broadcastQuotas() {
+	for _, quota := range p.Quotas() {
+		p.Hub.Broadcast <- websocket.Message{
+			Type: websocket.MessageTypeInfo,
+			Data: quota,
+		}
+	}
+}
+
+// ProcessorStats is exposed for monitoring
+type ProcessorStats struct {
+	Dedup DedupStats         `json:"dedup"`
+	Hub   websocket.HubStats `json:"hub"`
+}
+
+// Stats reports processor counters
+func (p *Processor) Stats() ProcessorStats {
+	return ProcessorStats{
+		Dedup: p.Dedup.Stats(),
+		Hub:   p.Hub.Stats(),
+	}
+}
+
+// SourceStatuses reports the health of every configured news source
+func (p *Processor) SourceStatuses() []SourceStatus {
+	statuses := make([]SourceStatus, 0, len(p.Sources))
+	for _, source := range p.Sources {
+		statuses = append(statuses, source.Status())
 	}
+	return statuses
 }
 
 func (p *Processor) ProcessArticle(article NewsArticle) {
-	text := p.NewsService.ExtractText(article)
+	text := ExtractText(article)
 	if text == "" {
 		return
 	}
 
+	if p.Dedup.CheckAndMark(article) {
+		return
+	}
+
 	// Analyze
-	emotion, intensity, err := p.EmotionService.AnalyzeEmotion(text)
+	result, err := p.Classifier.Classify(text)
 	if err != nil {
 		log.Printf("Error analyzing emotion: %v", err)
+		// let the next batch retry it
+		p.Dedup.Forget(article)
 		return
 	}
 
-	city, country, err := p.LocationService.ProcessLocation(article.Country)
+	p.publish(article, text, result)
+}
+
+// publish locates an analyzed article and broadcasts one point per location
+func (p *Processor) publish(article NewsArticle, text string, result EmotionResult) {
+	locations, err := p.LocationService.ProcessLocation(article)
 	if err != nil {
 		log.Printf("Error processing location: %v", err)
 		return
 	}
 
-	// coordinates
-	lat, lng, err := p.LocationService.GetCoordinates(city, country)
+	weight := 1.0
+	if p.SplitWeight {
+		weight /= float64(len(locations))
+	}
+	articleID := articleKey(article)
+
+	for _, location := range locations {
+		// coordinates; places found in the text already have them
+		if location.Precision == PrecisionCountry {
+			location.Lat, location.Lng, err = p.Geocoder.GetCoordinates(location.City, location.Country)
+			if err != nil {
+				log.Printf("Error getting coordinates for %s, %s: %v", location.City, location.Country, err)
+				continue
+			}
+		}
+		location.Lat, location.Lng = p.Jitter.Apply(articleID, location)
+
+		// emotion data
+		emotionData := websocket.EmotionData{
+			ArticleID:   articleID,
+			City:        location.City,
+			Region:      location.Region,
+			Country:     location.Country,
+			CountryCode: location.CountryCode,
+			Precision:   location.Precision,
+			Emotion:     result.Label,
+			Intensity:   result.Score,
+			Weight:      weight,
+			Lat:         location.Lat,
+			Lng:         location.Lng,
+			Text:        text[:min(100, len(text))],
+			Emotions:    result.Scores,
+		}
+		emotionData.ID = p.record(article, result, emotionData)
+
+		p.Hub.Broadcast <- websocket.Message{
+			Type: websocket.MessageTypeEmotion,
+			Data: emotionData,
+		}
+
+		log.Printf("Processed: %s - %.2f at %s", result.Label, result.Score, location)
+	}
+}
+
+// record saves a published point to the store and counts it in the rollup.
+// It returns the stored event's ID, or "" when it wasn't stored.
+func (p *Processor) record(article NewsArticle, result EmotionResult, emotionData websocket.EmotionData) string {
+	event := EmotionEvent{
+		Timestamp:   time.Now().UTC(),
+		Model:       result.Model,
+		EmotionData: emotionData,
+		Article:     newArticleMeta(article),
+	}
+
+	if p.Store != nil {
+		var err error
+		if event, err = p.Store.Append(event); err != nil {
+			log.Printf("Error recording event: %v", err)
+		}
+	}
+	p.Rollup.Add(event)
+	return event.ID
+}
+
+// Snapshot returns the recent events sent to clients when they connect
+func (p *Processor) Snapshot() []websocket.EmotionData {
+	if p.Store == nil || p.SnapshotSize <= 0 {
+		return nil
+	}
+
+	var since time.Time
+	if p.SnapshotWindow > 0 {
+		since = time.Now().Add(-p.SnapshotWindow)
+	}
+
+	events, err := p.Store.Recent(p.SnapshotSize, since)
 	if err != nil {
-		log.Printf("Error getting coordinates for %s, %s: %v", city, country, err)
-		return
+		log.Printf("Error loading snapshot: %v", err)
+		return nil
 	}
 
-	// emotion data
-	emotionData := websocket.EmotionData{
-		City:      city,
-		Country:   country,
-		Emotion:   emotion,
-		Intensity: intensity,
-		Lat:       lat,
-		Lng:       lng,
-		Text:      text[:min(100, len(text))],
+	snapshot := make([]websocket.EmotionData, len(events))
+	for i, event := range events {
+		snapshot[i] = event.Data()
 	}
+	return snapshot
+}
 
-	p.Hub.Broadcast <- websocket.Message{
-		Type: websocket.MessageTypeEmotion,
-		Data: emotionData,
+// articleKey identifies an article across the points it produced: its ID, or
+// a hash of its link or title for feeds without IDs
+func articleKey(article NewsArticle) string {
+	if id := strings.TrimSpace(article.ArticleID); id != "" {
+		return id
 	}
+	source := strings.TrimSpace(article.Link)
+	if source == "" {
+		source = normalizeTitle(article.Title)
+	}
+	sum := sha1.Sum([]byte(source))
+	return hex.EncodeToString(sum[:8])
+}
 
-	log.Printf("Processed: %s - %.2f at %s, %s", emotion, intensity, city, country)
+// splitWeight reads LOCATION_SPLIT_WEIGHT: when true an article placed in N
+// countries gives each point a weight of 1/N instead of 1
+func splitWeight() bool {
+	split, _ := strconv.ParseBool(os.Getenv("LOCATION_SPLIT_WEIGHT"))
+	return split
+}
+
+// emotionBatchSize reads EMOTION_BATCH_SIZE (default 8)
+func emotionBatchSize() int {
+	if env := os.Getenv("EMOTION_BATCH_SIZE"); env != "" {
+		if parsed, err := strconv.Atoi(env); err == nil && parsed > 0 {
+			return parsed
+		}
+	}
+	return 8
+}
+
+// snapshotSize reads SNAPSHOT_SIZE (default 100, 0 disables the snapshot)
+func snapshotSize() int {
+	if env := os.Getenv("SNAPSHOT_SIZE"); env != "" {
+		if parsed, err := strconv.Atoi(env); err == nil && parsed >= 0 {
+			return parsed
+		}
+	}
+	return 100
+}
+
+// snapshotWindow reads SNAPSHOT_WINDOW (default 0, no age limit)
+func snapshotWindow() time.Duration {
+	if env := os.Getenv("SNAPSHOT_WINDOW"); env != "" {
+		if parsed, err := time.ParseDuration(env); err == nil && parsed >= 0 {
+			return parsed
+		}
+	}
+	return 0
 }
 
 func min(a, b int) int {
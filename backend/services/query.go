@@ -0,0 +1,137 @@
+package services
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Page sizes for EventQuery
+const (
+	DefaultQueryLimit = 100
+	MaxQueryLimit     = 1000
+)
+
+// EventQuery filters stored events. Zero values don't filter.
+type EventQuery struct {
+	From         time.Time // inclusive
+	To           time.Time // exclusive
+	Country      string    // country name, matched case-insensitively
+	Emotion      string    // top label: happy, sad, angry, surprised or neutral
+	MinIntensity float64
+	Bounds       *Bounds
+	After        string // cursor: only events after this ID
+	Limit        int    // page size, DefaultQueryLimit when 0
+}
+
+// EventPage is one page of query results; Next is the cursor for the following
+// page and is empty on the last one
+type EventPage struct {
+	Events []EmotionEvent `json:"events"`
+	Next   string         `json:"next,omitempty"`
+}
+
+// Matches reports whether an event passes every filter except the cursor
+func (q EventQuery) Matches(event EmotionEvent) bool {
+	if !q.From.IsZero() && event.Timestamp.Before(q.From) {
+		return false
+	}
+	if !q.To.IsZero() && !event.Timestamp.Before(q.To) {
+		return false
+	}
+	if q.Country != "" && !strings.EqualFold(event.Country, q.Country) {
+		return false
+	}
+	if q.Emotion != "" && !strings.EqualFold(event.Emotion, q.Emotion) {
+		return false
+	}
+	if event.Intensity < q.MinIntensity {
+		return false
+	}
+	if q.Bounds != nil && !q.Bounds.Contains(event.Lat, event.Lng) {
+		return false
+	}
+	return true
+}
+
+// ParseEventQuery reads an EventQuery from URL parameters:
+//
+//	from, to       RFC 3339 timestamps
+//	country        ISO code or name
+//	emotion        label
+//	min_intensity  0-1
+//	bbox           minLng,minLat,maxLng,maxLat
+//	after, limit   pagination
+func ParseEventQuery(params url.Values) (EventQuery, error) {
+	var q EventQuery
+	var err error
+
+	if value := params.Get("from"); value != "" {
+		if q.From, err = time.Parse(time.RFC3339, value); err != nil {
+			return q, fmt.Errorf("invalid from: %w", err)
+		}
+	}
+	if value := params.Get("to"); value != "" {
+		if q.To, err = time.Parse(time.RFC3339, value); err != nil {
+			return q, fmt.Errorf("invalid to: %w", err)
+		}
+	}
+	if value := strings.TrimSpace(params.Get("country")); value != "" {
+		q.Country = mapCountryCode(value)
+	}
+	q.Emotion = strings.TrimSpace(params.Get("emotion"))
+
+	if value := params.Get("min_intensity"); value != "" {
+		if q.MinIntensity, err = strconv.ParseFloat(value, 64); err != nil {
+			return q, fmt.Errorf("invalid min_intensity: %w", err)
+		}
+	}
+	if value := params.Get("bbox"); value != "" {
+		bounds, err := parseBBox(value)
+		if err != nil {
+			return q, err
+		}
+		q.Bounds = &bounds
+	}
+
+	q.After = params.Get("after")
+	if value := params.Get("limit"); value != "" {
+		if q.Limit, err = strconv.Atoi(value); err != nil || q.Limit < 0 {
+			return q, fmt.Errorf("invalid limit: %s", value)
+		}
+	}
+	return q, nil
+}
+
+// parseBBox reads minLng,minLat,maxLng,maxLat (the GeoJSON order)
+func parseBBox(value string) (Bounds, error) {
+	parts := strings.Split(value, ",")
+	if len(parts) != 4 {
+		return Bounds{}, fmt.Errorf("invalid bbox: want minLng,minLat,maxLng,maxLat")
+	}
+
+	var coords [4]float64
+	for i, part := range parts {
+		parsed, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+		if err != nil {
+			return Bounds{}, fmt.Errorf("invalid bbox: %w", err)
+		}
+		coords[i] = parsed
+	}
+
+	bounds := Bounds{MinLng: coords[0], MinLat: coords[1], MaxLng: coords[2], MaxLat: coords[3]}
+	if bounds.MinLat > bounds.MaxLat {
+		return Bounds{}, fmt.Errorf("invalid bbox: minLat is above maxLat")
+	}
+	return bounds, nil
+}
+
+// pageLimit clamps a requested page size
+func pageLimit(limit int) int {
+	if limit <= 0 {
+		return DefaultQueryLimit
+	}
+	return min(limit, MaxQueryLimit)
+}
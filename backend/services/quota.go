@@ -0,0 +1,146 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"emotisphere/websocket"
+)
+
+// ErrQuotaExceeded is returned when a request would exceed the API budget
+var ErrQuotaExceeded = errors.New("API quota exceeded")
+
+// QuotaTracker counts requests against a daily credit cap. The window resets at
+// midnight UTC, which is when newsdata.io resets free tier credits.
+type QuotaTracker struct {
+	Source string
+	Limit  int // requests per day, 0 means unlimited
+
+	mu          sync.Mutex
+	windowStart time.Time
+	used        int
+	pausedUntil time.Time
+}
+
+// NewQuotaTracker creates a tracker allowing limit requests per day
+func NewQuotaTracker(source string, limit int) *QuotaTracker {
+	return &QuotaTracker{
+		Source:      source,
+		Limit:       limit,
+		windowStart: startOfDay(time.Now()),
+	}
+}
+
+// Acquire takes one credit, or fails with ErrQuotaExceeded while paused or out of budget
+func (q *QuotaTracker) Acquire() error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	now := time.Now()
+	q.rollover(now)
+
+	if now.Before(q.pausedUntil) {
+		return fmt.Errorf("%w: %s paused until %s", ErrQuotaExceeded, q.Source, q.pausedUntil.Format(time.RFC3339))
+	}
+
+	if q.Limit > 0 && q.used >= q.Limit {
+		q.pausedUntil = q.resetAt()
+		return fmt.Errorf("%w: %s used all %d daily requests", ErrQuotaExceeded, q.Source, q.Limit)
+	}
+
+	q.used++
+	return nil
+}
+
+// PauseUntil stops requests until the given time
+func (q *QuotaTracker) PauseUntil(until time.Time) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if until.After(q.pausedUntil) {
+		q.pausedUntil = until
+	}
+}
+
+// Exhaust marks the budget as spent until the window resets
+func (q *QuotaTracker) Exhaust() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.rollover(time.Now())
+	if q.Limit > 0 {
+		q.used = q.Limit
+	}
+	q.pausedUntil = q.resetAt()
+}
+
+// PausedUntil returns when requests resume, or the zero time when not paused
+func (q *QuotaTracker) PausedUntil() time.Time {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if time.Now().Before(q.pausedUntil) {
+		return q.pausedUntil
+	}
+	return time.Time{}
+}
+
+// Status reports the remaining budget
+func (q *QuotaTracker) Status() websocket.QuotaData {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	now := time.Now()
+	q.rollover(now)
+
+	status := websocket.QuotaData{
+		Kind:      websocket.InfoKindQuota,
+		Source:    q.Source,
+		Limit:     q.Limit,
+		Used:      q.used,
+		Remaining: -1,
+		ResetAt:   q.resetAt(),
+	}
+	if q.Limit > 0 {
+		status.Remaining = max(q.Limit-q.used, 0)
+	}
+	if now.Before(q.pausedUntil) {
+		status.PausedUntil = q.pausedUntil
+	}
+	return status
+}
+
+// rollover starts a new window once the day changed; callers hold mu
+func (q *QuotaTracker) rollover(now time.Time) {
+	if day := startOfDay(now); day.After(q.windowStart) {
+		q.windowStart = day
+		q.used = 0
+	}
+}
+
+func (q *QuotaTracker) resetAt() time.Time {
+	return q.windowStart.Add(24 * time.Hour)
+}
+
+func startOfDay(t time.Time) time.Time {
+	return t.UTC().Truncate(24 * time.Hour)
+}
+
+// parseRetryAfter reads a Retry-After header in either seconds or HTTP-date form
+func parseRetryAfter(header http.Header) (time.Duration, bool) {
+	value := header.Get("Retry-After")
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		return max(time.Until(date), 0), true
+	}
+	return 0, false
+}
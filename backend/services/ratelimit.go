@@ -0,0 +1,54 @@
+package services
+
+import (
+	"sync"
+	"time"
+)
+
+// RateLimiter spaces calls at least Interval apart, across all goroutines sharing it
+type RateLimiter struct {
+	Interval time.Duration
+
+	mu   sync.Mutex
+	next time.Time
+}
+
+// NewRateLimiter creates a limiter allowing one call per interval
+func NewRateLimiter(interval time.Duration) *RateLimiter {
+	return &RateLimiter{Interval: interval}
+}
+
+// Wait blocks until the caller may make its call
+func (rl *RateLimiter) Wait() {
+	rl.mu.Lock()
+	now := time.Now()
+	if rl.next.Before(now) {
+		rl.next = now
+	}
+	wait := rl.next.Sub(now)
+	rl.next = rl.next.Add(rl.Interval)
+	rl.mu.Unlock()
+
+	if wait > 0 {
+		time.Sleep(wait)
+	}
+}
+
+var (
+	sharedLimitersMu sync.Mutex
+	sharedLimiters   = make(map[string]*RateLimiter)
+)
+
+// sharedRateLimiter returns the process-wide limiter for a host, so every
+// client talking to the same service shares one budget
+func sharedRateLimiter(host string, interval time.Duration) *RateLimiter {
+	sharedLimitersMu.Lock()
+	defer sharedLimitersMu.Unlock()
+
+	limiter, ok := sharedLimiters[host]
+	if !ok {
+		limiter = NewRateLimiter(interval)
+		sharedLimiters[host] = limiter
+	}
+	return limiter
+}
@@ -0,0 +1,164 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math/rand"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+)
+
+// APIError is a failed call to an external API. Retryable tells callers whether
+// trying again later may succeed (rate limits, 5xx, model loading, network errors)
+// or whether the request is permanently wrong (bad key, unknown model, ...).
+type APIError struct {
+	Service    string
+	StatusCode int // 0 for network errors
+	Body       string
+	RetryAfter time.Duration // wait suggested by the server, 0 if none
+	Retryable  bool
+	Err        error // underlying network error, if any
+}
+
+func (e *APIError) Error() string {
+	if e.StatusCode == 0 {
+		return fmt.Sprintf("%s request failed: %v", e.Service, e.Err)
+	}
+	return fmt.Sprintf("%s API returned status %d: %s", e.Service, e.StatusCode, e.Body)
+}
+
+func (e *APIError) Unwrap() error {
+	return e.Err
+}
+
+// newAPIError classifies a non-200 response
+func newAPIError(service string, resp *http.Response, body []byte) *APIError {
+	apiErr := &APIError{
+		Service:    service,
+		StatusCode: resp.StatusCode,
+		Body:       string(body),
+	}
+
+	switch {
+	case resp.StatusCode == http.StatusTooManyRequests,
+		resp.StatusCode == http.StatusRequestTimeout,
+		resp.StatusCode >= 500:
+		apiErr.Retryable = true
+	}
+
+	if wait, ok := parseRetryAfter(resp.Header); ok {
+		apiErr.RetryAfter = wait
+	}
+
+	// Hugging Face reports how long a cold model needs to load:
+	// {"error": "Model ... is currently loading", "estimated_time": 20.0}
+	var loading struct {
+		EstimatedTime float64 `json:"estimated_time"`
+	}
+	if json.Unmarshal(body, &loading) == nil && loading.EstimatedTime > 0 && apiErr.RetryAfter == 0 {
+		apiErr.RetryAfter = time.Duration(loading.EstimatedTime * float64(time.Second))
+	}
+
+	return apiErr
+}
+
+// networkError wraps a transport failure; these are always worth retrying
+func networkError(service string, err error) *APIError {
+	return &APIError{Service: service, Retryable: true, Err: err}
+}
+
+// IsRetryable reports whether err is a temporary failure
+func IsRetryable(err error) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.Retryable
+}
+
+// hasStatus reports whether err is an APIError with one of the given status codes
+func hasStatus(err error, codes ...int) bool {
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+	for _, code := range codes {
+		if apiErr.StatusCode == code {
+			return true
+		}
+	}
+	return false
+}
+
+// RetryPolicy retries retryable errors with exponential backoff and full jitter
+type RetryPolicy struct {
+	MaxAttempts int
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration // also the longest Retry-After we are willing to wait
+}
+
+// NewRetryPolicy reads RETRY_MAX_ATTEMPTS, RETRY_BASE_DELAY and RETRY_MAX_DELAY
+func NewRetryPolicy() RetryPolicy {
+	policy := RetryPolicy{
+		MaxAttempts: 3,
+		BaseDelay:   time.Second,
+		MaxDelay:    30 * time.Second,
+	}
+
+	if env := os.Getenv("RETRY_MAX_ATTEMPTS"); env != "" {
+		if parsed, err := strconv.Atoi(env); err == nil && parsed > 0 {
+			policy.MaxAttempts = parsed
+		}
+	}
+	if env := os.Getenv("RETRY_BASE_DELAY"); env != "" {
+		if parsed, err := time.ParseDuration(env); err == nil && parsed > 0 {
+			policy.BaseDelay = parsed
+		}
+	}
+	if env := os.Getenv("RETRY_MAX_DELAY"); env != "" {
+		if parsed, err := time.ParseDuration(env); err == nil && parsed > 0 {
+			policy.MaxDelay = parsed
+		}
+	}
+	return policy
+}
+
+// Do calls fn until it succeeds, fails permanently or runs out of attempts
+func (rp RetryPolicy) Do(fn func() error) error {
+	attempts := max(rp.MaxAttempts, 1)
+
+	var err error
+	for attempt := 1; attempt <= attempts; attempt++ {
+		err = fn()
+		if err == nil || !IsRetryable(err) || attempt == attempts {
+			return err
+		}
+
+		delay := rp.backoff(attempt)
+		var apiErr *APIError
+		if errors.As(err, &apiErr) && apiErr.RetryAfter > 0 {
+			if apiErr.RetryAfter > rp.MaxDelay {
+				// not worth blocking the batch for, let the caller decide
+				return err
+			}
+			delay = apiErr.RetryAfter
+		}
+
+		log.Printf("Attempt %d/%d failed, retrying in %v: %v", attempt, attempts, delay.Round(time.Millisecond), err)
+		time.Sleep(delay)
+	}
+	return err
+}
+
+// backoff returns a random delay in [0, min(MaxDelay, BaseDelay*2^(attempt-1))]
+func (rp RetryPolicy) backoff(attempt int) time.Duration {
+	ceiling := rp.BaseDelay << (attempt - 1)
+	if ceiling <= 0 || ceiling > rp.MaxDelay {
+		ceiling = rp.MaxDelay
+	}
+	if ceiling <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(ceiling) + 1))
+}
@@ -0,0 +1,295 @@
+package services
+
+import (
+	"cmp"
+	"fmt"
+	"log"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Aggregation bucket sizes
+const (
+	BucketMinute = "minute"
+	BucketHour   = "hour"
+	BucketDay    = "day"
+)
+
+var bucketSizes = map[string]time.Duration{
+	BucketMinute: time.Minute,
+	BucketHour:   time.Hour,
+	BucketDay:    24 * time.Hour,
+}
+
+// Rollup keeps per-country counts for every bucket size, updated as events are
+// recorded, so aggregate queries read one entry per bucket instead of every event
+type Rollup struct {
+	MaxAge time.Duration // drop buckets that ended longer ago than this, 0 keeps all; for rollups not following a store
+
+	mu      sync.RWMutex
+	buckets map[string]map[rollupKey]*rollupStats // by bucket size
+	pruned  time.Time                             // last time old buckets were dropped
+}
+
+type rollupKey struct {
+	country string
+	start   int64 // unix seconds, UTC
+}
+
+type rollupStats struct {
+	country string // as first recorded
+	count   int
+	labels  map[string]*labelStats
+}
+
+type labelStats struct {
+	count        int
+	intensitySum float64
+}
+
+// AggregateBucket summarizes one country over one time bucket
+type AggregateBucket struct {
+	Country  string                      `json:"country"`
+	Start    time.Time                   `json:"start"`
+	Count    int                         `json:"count"`
+	Dominant string                      `json:"dominant"` // the most frequent top label
+	Emotions map[string]EmotionAggregate `json:"emotions"` // by top label
+}
+
+// EmotionAggregate summarizes the events of one bucket with the same top label
+type EmotionAggregate struct {
+	Count         int     `json:"count"`
+	MeanIntensity float64 `json:"mean_intensity"`
+}
+
+// AggregateQuery selects buckets. Zero values don't filter.
+type AggregateQuery struct {
+	Bucket  string    // BucketMinute, BucketHour or BucketDay
+	From    time.Time // buckets starting at or after
+	To      time.Time // buckets starting before
+	Country string    // country name, matched case-insensitively
+}
+
+// NewRollup creates an empty rollup
+func NewRollup() *Rollup {
+	r := &Rollup{buckets: make(map[string]map[rollupKey]*rollupStats, len(bucketSizes))}
+	for name := range bucketSizes {
+		r.buckets[name] = make(map[rollupKey]*rollupStats)
+	}
+	return r
+}
+
+// NewRollupFromStore builds a rollup over every event already in the store.
+// When the store is a TrimNotifier the rollup drops the events the store's
+// retention drops, so both hold the same events.
+func NewRollupFromStore(store Store) *Rollup {
+	r := NewRollup()
+	if store == nil {
+		return r
+	}
+
+	q := EventQuery{Limit: MaxQueryLimit}
+	for {
+		page, err := store.Query(q)
+		if err != nil {
+			log.Printf("Could not build rollup from event store: %v", err)
+			return r
+		}
+		for _, event := range page.Events {
+			r.Add(event)
+		}
+		if page.Next == "" {
+			break
+		}
+		q.After = page.Next
+	}
+
+	if notifier, ok := store.(TrimNotifier); ok {
+		notifier.OnTrim(func(dropped []EmotionEvent) {
+			for _, event := range dropped {
+				r.Remove(event)
+			}
+		})
+	}
+	return r
+}
+
+// Add counts an event in its minute, hour and day buckets
+func (r *Rollup) Add(event EmotionEvent) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	country := strings.ToLower(event.Country)
+	for name, size := range bucketSizes {
+		key := rollupKey{country: country, start: bucketStart(event.Timestamp, size).Unix()}
+		stats, ok := r.buckets[name][key]
+		if !ok {
+			stats = &rollupStats{country: event.Country, labels: make(map[string]*labelStats)}
+			r.buckets[name][key] = stats
+		}
+
+		stats.count++
+		label, ok := stats.labels[event.Emotion]
+		if !ok {
+			label = &labelStats{}
+			stats.labels[event.Emotion] = label
+		}
+		label.count++
+		label.intensitySum += event.Intensity
+	}
+
+	if now := time.Now(); r.MaxAge > 0 && now.Sub(r.pruned) >= time.Minute {
+		r.prune(now.Add(-r.MaxAge))
+		r.pruned = now
+	}
+}
+
+// Remove uncounts an event previously added. Events in buckets already
+// pruned are ignored.
+func (r *Rollup) Remove(event EmotionEvent) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	country := strings.ToLower(event.Country)
+	for name, size := range bucketSizes {
+		key := rollupKey{country: country, start: bucketStart(event.Timestamp, size).Unix()}
+		stats, ok := r.buckets[name][key]
+		if !ok {
+			continue
+		}
+		label, ok := stats.labels[event.Emotion]
+		if !ok {
+			continue
+		}
+
+		stats.count--
+		label.count--
+		label.intensitySum -= event.Intensity
+		if label.count == 0 {
+			delete(stats.labels, event.Emotion)
+		}
+		if stats.count == 0 {
+			delete(r.buckets[name], key)
+		}
+	}
+}
+
+// prune drops the buckets that ended before cutoff; callers hold mu
+func (r *Rollup) prune(cutoff time.Time) {
+	for name, size := range bucketSizes {
+		for key := range r.buckets[name] {
+			if time.Unix(key.start, 0).Add(size).Before(cutoff) {
+				delete(r.buckets[name], key)
+			}
+		}
+	}
+}
+
+// Aggregate returns the matching buckets sorted by country, then start time
+func (r *Rollup) Aggregate(q AggregateQuery) ([]AggregateBucket, error) {
+	buckets, ok := r.buckets[q.Bucket]
+	if !ok {
+		return nil, fmt.Errorf("invalid bucket %q: want minute, hour or day", q.Bucket)
+	}
+	country := strings.ToLower(q.Country)
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	result := []AggregateBucket{}
+	for key, stats := range buckets {
+		start := time.Unix(key.start, 0).UTC()
+		if country != "" && key.country != country {
+			continue
+		}
+		if !q.From.IsZero() && start.Before(q.From) {
+			continue
+		}
+		if !q.To.IsZero() && !start.Before(q.To) {
+			continue
+		}
+		result = append(result, stats.bucket(start))
+	}
+
+	slices.SortFunc(result, func(a, b AggregateBucket) int {
+		if c := cmp.Compare(a.Country, b.Country); c != 0 {
+			return c
+		}
+		return a.Start.Compare(b.Start)
+	})
+	return result, nil
+}
+
+// bucket converts the counters; callers hold the rollup's read lock
+func (s *rollupStats) bucket(start time.Time) AggregateBucket {
+	bucket := AggregateBucket{
+		Country:  s.country,
+		Start:    start,
+		Count:    s.count,
+		Emotions: make(map[string]EmotionAggregate, len(s.labels)),
+	}
+
+	for label, stats := range s.labels {
+		aggregate := EmotionAggregate{
+			Count:         stats.count,
+			MeanIntensity: stats.intensitySum / float64(stats.count),
+		}
+		bucket.Emotions[label] = aggregate
+	}
+
+	for label, aggregate := range bucket.Emotions {
+		if dominant, ok := bucket.Emotions[bucket.Dominant]; !ok || aggregate.outranks(label, dominant, bucket.Dominant) {
+			bucket.Dominant = label
+		}
+	}
+	return bucket
+}
+
+// outranks prefers the more frequent label; ties go to the stronger feeling,
+// then alphabetically so results are stable
+func (a EmotionAggregate) outranks(label string, other EmotionAggregate, otherLabel string) bool {
+	if a.Count != other.Count {
+		return a.Count > other.Count
+	}
+	if a.MeanIntensity != other.MeanIntensity {
+		return a.MeanIntensity > other.MeanIntensity
+	}
+	return label < otherLabel
+}
+
+func bucketStart(t time.Time, size time.Duration) time.Time {
+	return t.UTC().Truncate(size)
+}
+
+// ParseAggregateQuery reads an AggregateQuery from URL parameters: bucket
+// (default hour), from and to (RFC 3339) and country (ISO code or name)
+func ParseAggregateQuery(params url.Values) (AggregateQuery, error) {
+	q := AggregateQuery{Bucket: BucketHour}
+	var err error
+
+	if value := strings.ToLower(params.Get("bucket")); value != "" {
+		if _, ok := bucketSizes[value]; !ok {
+			return q, fmt.Errorf("invalid bucket %q: want minute, hour or day", value)
+		}
+		q.Bucket = value
+	}
+	if value := params.Get("from"); value != "" {
+		if q.From, err = time.Parse(time.RFC3339, value); err != nil {
+			return q, fmt.Errorf("invalid from: %w", err)
+		}
+		// include the bucket from falls in
+		q.From = bucketStart(q.From, bucketSizes[q.Bucket])
+	}
+	if value := params.Get("to"); value != "" {
+		if q.To, err = time.Parse(time.RFC3339, value); err != nil {
+			return q, fmt.Errorf("invalid to: %w", err)
+		}
+	}
+	if value := strings.TrimSpace(params.Get("country")); value != "" {
+		q.Country = mapCountryCode(value)
+	}
+	return q, nil
+}
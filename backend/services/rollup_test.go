@@ -0,0 +1,141 @@
+package services
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestRollupAggregate(t *testing.T) {
+	r := NewRollup()
+	start := time.Date(2025, 1, 1, 10, 0, 0, 0, time.UTC)
+
+	for _, event := range []EmotionEvent{
+		testEvent("Bolivia", start.Add(5*time.Minute)),
+		testEvent("bolivia", start.Add(50*time.Minute)),
+		testEvent("Bolivia", start.Add(70*time.Minute)),
+		testEvent("Peru", start.Add(10*time.Minute)),
+	} {
+		r.Add(event)
+	}
+
+	buckets, err := r.Aggregate(AggregateQuery{Bucket: BucketHour, Country: "Bolivia"})
+	if err != nil {
+		t.Fatalf("Aggregate: %v", err)
+	}
+	if len(buckets) != 2 {
+		t.Fatalf("got %d buckets, want 2", len(buckets))
+	}
+	if buckets[0].Count != 2 || !buckets[0].Start.Equal(start) || buckets[0].Dominant != "happy" {
+		t.Errorf("first bucket = %+v", buckets[0])
+	}
+	if buckets[1].Count != 1 || !buckets[1].Start.Equal(start.Add(time.Hour)) {
+		t.Errorf("second bucket = %+v", buckets[1])
+	}
+
+	if _, err := r.Aggregate(AggregateQuery{Bucket: "week"}); err == nil {
+		t.Error("expected an error for an unknown bucket size")
+	}
+}
+
+func TestRollupMaxAge(t *testing.T) {
+	r := NewRollup()
+	r.MaxAge = time.Hour
+
+	r.Add(testEvent("Bolivia", time.Now().Add(-3*24*time.Hour)))
+	r.Add(testEvent("Bolivia", time.Now()))
+
+	for _, bucket := range []string{BucketMinute, BucketHour, BucketDay} {
+		buckets, err := r.Aggregate(AggregateQuery{Bucket: bucket})
+		if err != nil {
+			t.Fatalf("Aggregate: %v", err)
+		}
+		if len(buckets) != 1 || buckets[0].Count != 1 {
+			t.Errorf("%s buckets = %+v, want only the recent event", bucket, buckets)
+		}
+	}
+}
+
+func TestRollupRemove(t *testing.T) {
+	r := NewRollup()
+	start := time.Date(2025, 1, 1, 10, 0, 0, 0, time.UTC)
+
+	sad := testEvent("Bolivia", start)
+	sad.Emotion = "sad"
+	r.Add(testEvent("Bolivia", start))
+	r.Add(sad)
+	r.Remove(sad)
+	r.Remove(testEvent("Peru", start)) // never added
+
+	buckets, err := r.Aggregate(AggregateQuery{Bucket: BucketHour})
+	if err != nil {
+		t.Fatalf("Aggregate: %v", err)
+	}
+	if len(buckets) != 1 || buckets[0].Count != 1 || len(buckets[0].Emotions) != 1 || buckets[0].Dominant != "happy" {
+		t.Fatalf("buckets = %+v, want one happy event", buckets)
+	}
+
+	r.Remove(testEvent("Bolivia", start))
+	if buckets, _ := r.Aggregate(AggregateQuery{Bucket: BucketHour}); len(buckets) != 0 {
+		t.Errorf("buckets = %+v, want none once every event is removed", buckets)
+	}
+}
+
+// TestRollupFollowsStoreRetention checks the rollup drops the events the
+// store's MaxEvents drops, so it agrees with a rollup rebuilt after a restart
+func TestRollupFollowsStoreRetention(t *testing.T) {
+	const maxEvents = 10
+
+	path := filepath.Join(t.TempDir(), "events.jsonl")
+	store, err := OpenFileStore(path, Retention{MaxEvents: maxEvents})
+	if err != nil {
+		t.Fatalf("OpenFileStore: %v", err)
+	}
+	rollup := NewRollupFromStore(store)
+
+	start := time.Now().UTC().Add(-time.Hour)
+	for i := 0; i < 5*maxEvents; i++ {
+		// as Processor.record does
+		event, err := store.Append(testEvent("Bolivia", start.Add(time.Duration(i)*time.Minute)))
+		if err != nil {
+			t.Fatalf("Append: %v", err)
+		}
+		rollup.Add(event)
+	}
+
+	stored := len(store.events)
+	if counted := countEvents(t, rollup); counted != stored {
+		t.Errorf("rollup counts %d events, store holds %d", counted, stored)
+	}
+
+	if err := store.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	reopened, err := OpenFileStore(path, Retention{MaxEvents: maxEvents})
+	if err != nil {
+		t.Fatalf("OpenFileStore: %v", err)
+	}
+	defer reopened.Close()
+
+	want, _ := rollup.Aggregate(AggregateQuery{Bucket: BucketMinute})
+	got, _ := NewRollupFromStore(reopened).Aggregate(AggregateQuery{Bucket: BucketMinute})
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("rebuilt rollup = %+v, want %+v", got, want)
+	}
+}
+
+// countEvents sums a rollup's day buckets
+func countEvents(t *testing.T, r *Rollup) int {
+	t.Helper()
+
+	buckets, err := r.Aggregate(AggregateQuery{Bucket: BucketDay})
+	if err != nil {
+		t.Fatalf("Aggregate: %v", err)
+	}
+	count := 0
+	for _, bucket := range buckets {
+		count += bucket.Count
+	}
+	return count
+}
@@ -0,0 +1,339 @@
+package services
+
+import (
+	"encoding/xml"
+	"fmt"
+	"html"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// FeedConfig is a single RSS/Atom feed and the country its articles are tagged with
+type FeedConfig struct {
+	URL     string
+	Country string
+}
+
+// feedCache remembers the validators of the last successful download of a feed
+type feedCache struct {
+	ETag         string
+	LastModified string
+}
+
+// RSSService reads RSS 2.0 and Atom feeds
+type RSSService struct {
+	Feeds    []FeedConfig
+	Client   *http.Client
+	MaxItems int // max items taken from each feed, 0 means no limit
+
+	mu     sync.Mutex
+	cache  map[string]feedCache
+	health sourceHealth
+}
+
+// NewRSSService creates a feed reader from RSS_FEEDS.
+// RSS_FEEDS is a comma-separated list of feed URLs, each optionally prefixed
+// with a country code: "us=https://example.com/rss.xml,https://example.org/atom.xml"
+func NewRSSService() *RSSService {
+	maxItems := 20
+	if env := os.Getenv("RSS_MAX_ITEMS"); env != "" {
+		if parsed, err := strconv.Atoi(env); err == nil && parsed >= 0 {
+			maxItems = parsed
+		}
+	}
+
+	return &RSSService{
+		Feeds: parseFeedConfig(os.Getenv("RSS_FEEDS")),
+		Client: &http.Client{
+			Timeout: 30 * time.Second,
+		},
+		MaxItems: maxItems,
+		cache:    make(map[string]feedCache),
+	}
+}
+
+func parseFeedConfig(value string) []FeedConfig {
+	var feeds []FeedConfig
+	for _, part := range strings.Split(value, ",") {
+		entry := strings.TrimSpace(part)
+		if entry == "" {
+			continue
+		}
+
+		feed := FeedConfig{URL: entry}
+		if prefix, rest, ok := strings.Cut(entry, "="); ok && !strings.ContainsAny(prefix, ":/") {
+			feed.Country = strings.ToLower(strings.TrimSpace(prefix))
+			feed.URL = strings.TrimSpace(rest)
+		}
+		feeds = append(feeds, feed)
+	}
+	return feeds
+}
+
+// Name implements NewsSource
+func (rs *RSSService) Name() string {
+	return "rss"
+}
+
+// FetchArticles implements NewsSource. Every configured feed is read whatever
+// the regions: a feed's country only tags its articles, so a feed listed in
+// RSS_FEEDS is always wanted.
+func (rs *RSSService) FetchArticles(regions []string) ([]NewsArticle, error) {
+	if len(rs.Feeds) == 0 {
+		err := fmt.Errorf("RSS_FEEDS not set in environment variables")
+		rs.health.record(rs.Name(), 0, err)
+		return nil, err
+	}
+
+	var articles []NewsArticle
+	var lastErr error
+	failed := 0
+	for _, feed := range rs.Feeds {
+		feedArticles, err := rs.FetchFeed(feed)
+		if err != nil {
+			log.Printf("Could not fetch feed %s: %v", feed.URL, err)
+			lastErr = err
+			failed++
+			continue
+		}
+		articles = append(articles, feedArticles...)
+	}
+
+	if failed == len(rs.Feeds) {
+		rs.health.record(rs.Name(), 0, lastErr)
+		return nil, fmt.Errorf("all feeds failed, last error: %w", lastErr)
+	}
+
+	rs.health.record(rs.Name(), len(articles), nil)
+	return articles, nil
+}
+
+// Status implements NewsSource
+func (rs *RSSService) Status() SourceStatus {
+	status := rs.health.snapshot(rs.Name())
+	status.Remaining = -1
+	return status
+}
+
+// Configured implements NewsSource
+func (rs *RSSService) Configured() bool {
+	return len(rs.Feeds) > 0
+}
+
+// FetchFeed downloads and parses a single feed. Returns no articles when the
+// server reports the feed unchanged since the previous download.
+func (rs *RSSService) FetchFeed(feed FeedConfig) ([]NewsArticle, error) {
+	req, err := http.NewRequest("GET", feed.URL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("User-Agent", "Emotisphere/1.0")
+	req.Header.Set("Accept", "application/rss+xml, application/atom+xml, application/xml;q=0.9, */*;q=0.8")
+
+	rs.mu.Lock()
+	cached, ok := rs.cache[feed.URL]
+	rs.mu.Unlock()
+	if ok {
+		if cached.ETag != "" {
+			req.Header.Set("If-None-Match", cached.ETag)
+		}
+		if cached.LastModified != "" {
+			req.Header.Set("If-Modified-Since", cached.LastModified)
+		}
+	}
+
+	resp, err := rs.Client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch feed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotModified {
+		return []NewsArticle{}, nil
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("feed returned status %d", resp.StatusCode)
+	}
+
+	articles, err := parseFeed(body, feed.Country)
+	if err != nil {
+		return nil, err
+	}
+
+	rs.mu.Lock()
+	rs.cache[feed.URL] = feedCache{
+		ETag:         resp.Header.Get("ETag"),
+		LastModified: resp.Header.Get("Last-Modified"),
+	}
+	rs.mu.Unlock()
+
+	if rs.MaxItems > 0 && len(articles) > rs.MaxItems {
+		articles = articles[:rs.MaxItems]
+	}
+	return articles, nil
+}
+
+// rssDocument is an RSS 2.0 document
+type rssDocument struct {
+	XMLName xml.Name `xml:"rss"`
+	Channel struct {
+		Language string    `xml:"language"`
+		Items    []rssItem `xml:"item"`
+	} `xml:"channel"`
+}
+
+type rssItem struct {
+	Title       string `xml:"title"`
+	Link        string `xml:"link"`
+	GUID        string `xml:"guid"`
+	Description string `xml:"description"`
+	Content     string `xml:"encoded"` // content:encoded
+	PubDate     string `xml:"pubDate"`
+	Date        string `xml:"date"` // dc:date
+}
+
+// atomDocument is an Atom 1.0 document
+type atomDocument struct {
+	XMLName xml.Name    `xml:"feed"`
+	Lang    string      `xml:"lang,attr"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomEntry struct {
+	ID    string `xml:"id"`
+	Title string `xml:"title"`
+	Links []struct {
+		Href string `xml:"href,attr"`
+		Rel  string `xml:"rel,attr"`
+	} `xml:"link"`
+	Summary   string `xml:"summary"`
+	Content   string `xml:"content"`
+	Published string `xml:"published"`
+	Updated   string `xml:"updated"`
+}
+
+// parseFeed parses an RSS 2.0 or Atom document into articles tagged with country
+func parseFeed(body []byte, country string) ([]NewsArticle, error) {
+	var countries []string
+	if country != "" {
+		countries = []string{country}
+	}
+
+	var root struct {
+		XMLName xml.Name
+	}
+	if err := xml.Unmarshal(body, &root); err != nil {
+		return nil, fmt.Errorf("failed to parse feed: %w", err)
+	}
+
+	var articles []NewsArticle
+	switch root.XMLName.Local {
+	case "rss":
+		var doc rssDocument
+		if err := xml.Unmarshal(body, &doc); err != nil {
+			return nil, fmt.Errorf("failed to parse RSS feed: %w", err)
+		}
+		for _, item := range doc.Channel.Items {
+			pubDate := item.PubDate
+			if pubDate == "" {
+				pubDate = item.Date
+			}
+			articles = append(articles, NewsArticle{
+				ArticleID:   strings.TrimSpace(item.GUID),
+				Title:       cleanFeedText(item.Title),
+				Description: cleanFeedText(item.Description),
+				Content:     cleanFeedText(item.Content),
+				Link:        strings.TrimSpace(item.Link),
+				Country:     countries,
+				Language:    doc.Channel.Language,
+				PubDate:     normalizeFeedDate(pubDate),
+			})
+		}
+
+	case "feed":
+		var doc atomDocument
+		if err := xml.Unmarshal(body, &doc); err != nil {
+			return nil, fmt.Errorf("failed to parse Atom feed: %w", err)
+		}
+		for _, entry := range doc.Entries {
+			link := ""
+			for _, l := range entry.Links {
+				if l.Rel == "" || l.Rel == "alternate" {
+					link = l.Href
+					break
+				}
+			}
+			pubDate := entry.Published
+			if pubDate == "" {
+				pubDate = entry.Updated
+			}
+			articles = append(articles, NewsArticle{
+				ArticleID:   strings.TrimSpace(entry.ID),
+				Title:       cleanFeedText(entry.Title),
+				Description: cleanFeedText(entry.Summary),
+				Content:     cleanFeedText(entry.Content),
+				Link:        strings.TrimSpace(link),
+				Country:     countries,
+				Language:    doc.Lang,
+				PubDate:     normalizeFeedDate(pubDate),
+			})
+		}
+
+	default:
+		return nil, fmt.Errorf("unsupported feed format <%s>", root.XMLName.Local)
+	}
+
+	return articles, nil
+}
+
+var (
+	htmlTagPattern    = regexp.MustCompile(`<[^>]*>`)
+	whitespacePattern = regexp.MustCompile(`\s+`)
+)
+
+// cleanFeedText strips HTML markup and collapses whitespace
+func cleanFeedText(text string) string {
+	text = htmlTagPattern.ReplaceAllString(text, " ")
+	text = html.UnescapeString(text)
+	return strings.TrimSpace(whitespacePattern.ReplaceAllString(text, " "))
+}
+
+// feedDateLayouts are the date formats seen in the wild in RSS and Atom feeds
+var feedDateLayouts = []string{
+	time.RFC1123Z,
+	time.RFC1123,
+	time.RFC3339,
+	"Mon, 2 Jan 2006 15:04:05 -0700",
+	"Mon, 2 Jan 2006 15:04:05 MST",
+	"2 Jan 2006 15:04:05 -0700",
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+}
+
+// newsDateLayout is the pubDate format used by newsdata.io
+const newsDateLayout = "2006-01-02 15:04:05"
+
+// normalizeFeedDate converts a feed date to the newsdata.io pubDate format (UTC)
+func normalizeFeedDate(value string) string {
+	value = strings.TrimSpace(value)
+	for _, layout := range feedDateLayouts {
+		if parsed, err := time.Parse(layout, value); err == nil {
+			return parsed.UTC().Format(newsDateLayout)
+		}
+	}
+	return value
+}
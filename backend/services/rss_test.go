@@ -0,0 +1,180 @@
+package services
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync/atomic"
+	"testing"
+)
+
+const testRSSFeed = `<?xml version="1.0" encoding="UTF-8"?>
+<rss version="2.0" xmlns:content="http://purl.org/rss/1.0/modules/content/">
+  <channel>
+    <language>en</language>
+    <item>
+      <title>Floods &amp; storms</title>
+      <link>https://example.com/floods</link>
+      <guid>floods-1</guid>
+      <description><![CDATA[<p>Heavy   rain in <b>Lisbon</b></p>]]></description>
+      <content:encoded><![CDATA[<div>Full story</div>]]></content:encoded>
+      <pubDate>Mon, 02 Jan 2006 15:04:05 -0700</pubDate>
+    </item>
+  </channel>
+</rss>`
+
+const testAtomFeed = `<?xml version="1.0" encoding="UTF-8"?>
+<feed xmlns="http://www.w3.org/2005/Atom" xml:lang="fr">
+  <entry>
+    <id>urn:entry:1</id>
+    <title>Festival opens</title>
+    <link rel="self" href="https://example.org/self"/>
+    <link rel="alternate" href="https://example.org/festival"/>
+    <summary>Crowds gather</summary>
+    <updated>2006-01-02T22:04:05Z</updated>
+  </entry>
+</feed>`
+
+func TestParseFeedRSS(t *testing.T) {
+	articles, err := parseFeed([]byte(testRSSFeed), "pt")
+	if err != nil {
+		t.Fatalf("parseFeed: %v", err)
+	}
+	if len(articles) != 1 {
+		t.Fatalf("got %d articles, want 1", len(articles))
+	}
+
+	got := articles[0]
+	want := NewsArticle{
+		ArticleID:   "floods-1",
+		Title:       "Floods & storms",
+		Link:        "https://example.com/floods",
+		Description: "Heavy rain in Lisbon",
+		Content:     "Full story",
+		Country:     []string{"pt"},
+		Language:    "en",
+		PubDate:     "2006-01-02 22:04:05",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+}
+
+func TestParseFeedAtom(t *testing.T) {
+	articles, err := parseFeed([]byte(testAtomFeed), "")
+	if err != nil {
+		t.Fatalf("parseFeed: %v", err)
+	}
+	if len(articles) != 1 {
+		t.Fatalf("got %d articles, want 1", len(articles))
+	}
+
+	got := articles[0]
+	if got.ArticleID != "urn:entry:1" || got.Title != "Festival opens" || got.Description != "Crowds gather" {
+		t.Errorf("unexpected article %+v", got)
+	}
+	if got.Link != "https://example.org/festival" {
+		t.Errorf("Link = %q, want the alternate link", got.Link)
+	}
+	if got.Language != "fr" || got.PubDate != "2006-01-02 22:04:05" || got.Country != nil {
+		t.Errorf("unexpected metadata %+v", got)
+	}
+}
+
+func TestParseFeedUnsupported(t *testing.T) {
+	if _, err := parseFeed([]byte(`<html></html>`), ""); err == nil {
+		t.Error("expected an error for a non-feed document")
+	}
+}
+
+func TestFetchFeedConditionalGet(t *testing.T) {
+	const etag = `"v1"`
+	const lastModified = "Mon, 02 Jan 2006 15:04:05 GMT"
+
+	var requests, notModified atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		if r.Header.Get("If-None-Match") == etag && r.Header.Get("If-Modified-Since") == lastModified {
+			notModified.Add(1)
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", etag)
+		w.Header().Set("Last-Modified", lastModified)
+		w.Write([]byte(testRSSFeed))
+	}))
+	defer server.Close()
+
+	rs := &RSSService{Client: server.Client(), cache: make(map[string]feedCache)}
+	feed := FeedConfig{URL: server.URL}
+
+	articles, err := rs.FetchFeed(feed)
+	if err != nil {
+		t.Fatalf("first fetch: %v", err)
+	}
+	if len(articles) != 1 {
+		t.Fatalf("first fetch returned %d articles, want 1", len(articles))
+	}
+
+	articles, err = rs.FetchFeed(feed)
+	if err != nil {
+		t.Fatalf("second fetch: %v", err)
+	}
+	if len(articles) != 0 {
+		t.Errorf("unchanged feed returned %d articles, want 0", len(articles))
+	}
+	if requests.Load() != 2 || notModified.Load() != 1 {
+		t.Errorf("got %d requests with %d not modified, want 2 and 1", requests.Load(), notModified.Load())
+	}
+}
+
+func TestFetchArticlesReadsFeedsOutsideRegions(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/down" {
+			http.Error(w, "down", http.StatusInternalServerError)
+			return
+		}
+		w.Write([]byte(testRSSFeed))
+	}))
+	defer server.Close()
+
+	rs := &RSSService{
+		Client: server.Client(),
+		Feeds: []FeedConfig{
+			{URL: server.URL + "/down", Country: "us"},
+			{URL: server.URL + "/gb", Country: "gb"}, // not one of the regions
+		},
+		cache: make(map[string]feedCache),
+	}
+
+	articles, err := rs.FetchArticles([]string{"us", "br"})
+	if err != nil {
+		t.Fatalf("FetchArticles: %v", err)
+	}
+	if len(articles) != 1 || articles[0].Country[0] != "gb" {
+		t.Errorf("got %+v, want the gb feed's article", articles)
+	}
+}
+
+func TestFetchArticlesAllFeedsFail(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "down", http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	rs := &RSSService{
+		Client: server.Client(),
+		Feeds: []FeedConfig{
+			{URL: server.URL + "/us", Country: "us"},
+			{URL: server.URL + "/gb", Country: "gb"},
+		},
+		cache: make(map[string]feedCache),
+	}
+
+	if _, err := rs.FetchArticles([]string{"us"}); err == nil {
+		t.Fatal("expected an error when every feed fails")
+	}
+	if rs.Status().Healthy {
+		t.Error("source reported healthy after every feed failed")
+	}
+}
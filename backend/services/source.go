@@ -0,0 +1,172 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"emotisphere/websocket"
+)
+
+// NewsSource is a provider of news articles for a set of regions
+type NewsSource interface {
+	// Name identifies the source in logs and status reports
+	Name() string
+
+	// FetchArticles fetches recent articles for the given region codes
+	FetchArticles(regions []string) ([]NewsArticle, error)
+
+	// Status reports the current health and quota of the source
+	Status() SourceStatus
+
+	// Configured reports whether the source has what it needs to fetch
+	// (an API key, feed URLs, ...)
+	Configured() bool
+}
+
+// SourceStatus describes the health of a news source
+type SourceStatus struct {
+	Name        string    `json:"name"`
+	Healthy     bool      `json:"healthy"`
+	LastFetch   time.Time `json:"last_fetch,omitzero"`
+	LastError   string    `json:"last_error,omitempty"`
+	Articles    int       `json:"articles"`  // articles returned by the last fetch
+	Remaining   int       `json:"remaining"` // remaining requests, -1 when unknown
+	PausedUntil time.Time `json:"paused_until,omitzero"`
+}
+
+// QuotaReporter is implemented by sources with a request budget
+type QuotaReporter interface {
+	QuotaStatus() websocket.QuotaData
+}
+
+// sourceHealth keeps the bookkeeping shared by every source implementation
+type sourceHealth struct {
+	mu     sync.Mutex
+	status SourceStatus
+}
+
+func (h *sourceHealth) record(name string, articles int, err error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.status.Name = name
+	h.status.LastFetch = time.Now()
+	h.status.Articles = articles
+	h.status.Healthy = err == nil
+	if err != nil {
+		h.status.LastError = err.Error()
+	} else {
+		h.status.LastError = ""
+	}
+}
+
+func (h *sourceHealth) snapshot(name string) SourceStatus {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	status := h.status
+	status.Name = name
+	if status.LastFetch.IsZero() {
+		// never fetched, assume healthy until proven otherwise
+		status.Healthy = true
+	}
+	return status
+}
+
+// NewNewsSources builds the news sources listed in NEWS_SOURCES (comma-separated).
+// Defaults to newsdata.io only.
+func NewNewsSources() []NewsSource {
+	names := []string{"newsdata"}
+	if env := os.Getenv("NEWS_SOURCES"); env != "" {
+		names = []string{}
+		for _, part := range strings.Split(env, ",") {
+			if trimmed := strings.TrimSpace(part); trimmed != "" {
+				names = append(names, trimmed)
+			}
+		}
+	}
+
+	var sources []NewsSource
+	for _, name := range names {
+		source, err := NewNewsSource(name)
+		if err != nil {
+			log.Printf("Skipping news source: %v", err)
+			continue
+		}
+		sources = append(sources, source)
+	}
+	return sources
+}
+
+// NewNewsSource creates a news source by its configuration name
+func NewNewsSource(name string) (NewsSource, error) {
+	switch strings.ToLower(name) {
+	case "newsdata", "newsdata.io":
+		return NewNewsService(), nil
+	case "rss", "atom":
+		return NewRSSService(), nil
+	default:
+		return nil, fmt.Errorf("unknown news source %q", name)
+	}
+}
+
+// AnyConfigured reports whether at least one of the sources can fetch
+func AnyConfigured(sources []NewsSource) bool {
+	for _, source := range sources {
+		if source.Configured() {
+			return true
+		}
+	}
+	return false
+}
+
+// FetchAll fetches from every source concurrently and merges the results.
+// An error is only returned when every source failed.
+func FetchAll(sources []NewsSource, regions []string) ([]NewsArticle, error) {
+	if len(sources) == 0 {
+		return nil, fmt.Errorf("no news sources configured")
+	}
+
+	type fetchResult struct {
+		source   string
+		articles []NewsArticle
+		err      error
+	}
+
+	results := make(chan fetchResult, len(sources))
+	var wg sync.WaitGroup
+	for _, source := range sources {
+		wg.Add(1)
+		go func(src NewsSource) {
+			defer wg.Done()
+			articles, err := src.FetchArticles(regions)
+			results <- fetchResult{source: src.Name(), articles: articles, err: err}
+		}(source)
+	}
+	wg.Wait()
+	close(results)
+
+	var articles []NewsArticle
+	var errs []string
+	for result := range results {
+		if result.err != nil {
+			log.Printf("Error fetching from %s: %v", result.source, result.err)
+			errs = append(errs, fmt.Sprintf("%s: %v", result.source, result.err))
+			continue
+		}
+		log.Printf("Fetched %d articles from %s", len(result.articles), result.source)
+		for i := range result.articles {
+			result.articles[i].Source = result.source
+		}
+		articles = append(articles, result.articles...)
+	}
+
+	if len(errs) == len(sources) {
+		return nil, fmt.Errorf("all news sources failed: %s", strings.Join(errs, "; "))
+	}
+	return articles, nil
+}
@@ -0,0 +1,15 @@
+package services
+
+import "testing"
+
+func TestAnyConfigured(t *testing.T) {
+	rssOnly := []NewsSource{&RSSService{Feeds: parseFeedConfig("https://example.com/rss.xml")}}
+	if !AnyConfigured(rssOnly) {
+		t.Error("an RSS source with feeds should count as configured")
+	}
+
+	unconfigured := []NewsSource{&NewsService{}, &RSSService{}}
+	if AnyConfigured(unconfigured) {
+		t.Error("sources without a key or feeds should not count as configured")
+	}
+}
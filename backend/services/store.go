@@ -0,0 +1,170 @@
+package services
+
+import (
+	"errors"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"emotisphere/websocket"
+)
+
+// ErrEventNotFound is returned when a store has no event with the requested ID
+var ErrEventNotFound = errors.New("event not found")
+
+// EmotionEvent is one broadcast emotion point as recorded by a Store. ID
+// shadows EmotionData.ID, which is left empty in storage.
+type EmotionEvent struct {
+	ID        string    `json:"id"`
+	Timestamp time.Time `json:"timestamp"`
+	Model     string    `json:"model"`
+	websocket.EmotionData
+	Article ArticleMeta `json:"article"`
+}
+
+// ArticleMeta is the part of the source article kept with each event
+type ArticleMeta struct {
+	ID       string   `json:"id,omitempty"`
+	Title    string   `json:"title"`
+	Link     string   `json:"link,omitempty"`
+	Source   string   `json:"source,omitempty"`
+	Country  []string `json:"country,omitempty"`
+	Language string   `json:"language,omitempty"`
+	PubDate  string   `json:"pub_date,omitempty"`
+}
+
+// Data returns the event as broadcast to clients
+func (e EmotionEvent) Data() websocket.EmotionData {
+	data := e.EmotionData
+	data.ID = e.ID
+	return data
+}
+
+// newArticleMeta copies an article's metadata, leaving out its text
+func newArticleMeta(article NewsArticle) ArticleMeta {
+	return ArticleMeta{
+		ID:       article.ArticleID,
+		Title:    article.Title,
+		Link:     article.Link,
+		Source:   article.Source,
+		Country:  article.Country,
+		Language: article.Language,
+		PubDate:  article.PubDate,
+	}
+}
+
+// Store records emotion events
+type Store interface {
+	// Append records an event, assigning its ID, and returns the stored event
+	Append(event EmotionEvent) (EmotionEvent, error)
+
+	// Get returns the event with the given ID, or ErrEventNotFound
+	Get(id string) (EmotionEvent, error)
+
+	// Recent returns up to limit of the latest events recorded at or after
+	// since (zero means no lower bound), oldest first
+	Recent(limit int, since time.Time) ([]EmotionEvent, error)
+
+	// Query returns a page of the events matching q, oldest first
+	Query(q EventQuery) (EventPage, error)
+
+	// Close flushes and releases the store
+	Close() error
+}
+
+// TrimNotifier is implemented by stores that report the events their
+// retention drops, so views built from the store can drop them too
+type TrimNotifier interface {
+	// OnTrim sets fn to be called with the dropped events, oldest first, while
+	// the store is locked
+	OnTrim(fn func(dropped []EmotionEvent))
+}
+
+// Retention bounds the history a store keeps. Zero fields don't limit. Stores
+// trim in batches, so up to a tenth more may be kept between trims, and the
+// latest event is always kept so IDs keep increasing across restarts.
+type Retention struct {
+	MaxAge    time.Duration // drop events older than this
+	MaxEvents int           // keep at most this many of the latest events
+}
+
+// RetentionFromEnv reads EVENT_STORE_RETENTION (default 30 days) and
+// EVENT_STORE_MAX_EVENTS (default 100000); 0 disables either limit
+func RetentionFromEnv() Retention {
+	retention := Retention{
+		MaxAge:    30 * 24 * time.Hour,
+		MaxEvents: 100000,
+	}
+
+	if env := os.Getenv("EVENT_STORE_RETENTION"); env != "" {
+		if parsed, err := time.ParseDuration(env); err == nil && parsed >= 0 {
+			retention.MaxAge = parsed
+		}
+	}
+	if env := os.Getenv("EVENT_STORE_MAX_EVENTS"); env != "" {
+		if parsed, err := strconv.Atoi(env); err == nil && parsed >= 0 {
+			retention.MaxEvents = parsed
+		}
+	}
+	return retention
+}
+
+// expired counts the leading events, oldest first, that fall outside the limits
+func (r Retention) expired(events []EmotionEvent, now time.Time) int {
+	n := 0
+	if r.MaxEvents > 0 && len(events) > r.MaxEvents {
+		n = len(events) - r.MaxEvents
+	}
+	if r.MaxAge > 0 {
+		cutoff := now.Add(-r.MaxAge)
+		for n < len(events) && events[n].Timestamp.Before(cutoff) {
+			n++
+		}
+	}
+	return min(n, max(len(events)-1, 0))
+}
+
+// due reports whether enough events expired to be worth a trim
+func (r Retention) due(events []EmotionEvent, now time.Time) bool {
+	slack := max(len(events)/10, 1)
+	if r.MaxEvents > 0 && len(events) > r.MaxEvents+slack {
+		return true
+	}
+	return r.MaxAge > 0 && len(events) > slack && events[slack-1].Timestamp.Before(now.Add(-r.MaxAge))
+}
+
+// NewStoreFromEnv creates the store named by EVENT_STORE: "file" (default,
+// an append-only log at EVENT_STORE_FILE), "memory" (lost on restart) or
+// "off" (nil, nothing is recorded). Both keep the history RetentionFromEnv allows.
+func NewStoreFromEnv() Store {
+	retention := RetentionFromEnv()
+
+	switch strings.ToLower(os.Getenv("EVENT_STORE")) {
+	case "off", "none":
+		return nil
+	case "memory":
+		store := NewMemoryStore()
+		store.Retention = retention
+		return store
+	case "", "file":
+	default:
+		log.Printf("Unknown EVENT_STORE %q, using file", os.Getenv("EVENT_STORE"))
+	}
+
+	path := filepath.Join("data", "events.jsonl")
+	if env := os.Getenv("EVENT_STORE_FILE"); env != "" {
+		path = env
+	}
+
+	store, err := OpenFileStore(path, retention)
+	if err != nil {
+		log.Printf("Could not open event store %s, keeping events in memory: %v", path, err)
+		memory := NewMemoryStore()
+		memory.Retention = retention
+		return memory
+	}
+	return store
+}
@@ -0,0 +1,178 @@
+package services
+
+import (
+	"bufio"
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"emotisphere/websocket"
+)
+
+func testEvent(country string, at time.Time) EmotionEvent {
+	return EmotionEvent{
+		Timestamp:   at,
+		EmotionData: websocket.EmotionData{Country: country, Emotion: "happy", Intensity: 0.5},
+	}
+}
+
+func appendEvents(t *testing.T, store Store, n int, at time.Time) []EmotionEvent {
+	t.Helper()
+
+	events := make([]EmotionEvent, n)
+	for i := range events {
+		event, err := store.Append(testEvent("Bolivia", at))
+		if err != nil {
+			t.Fatalf("Append: %v", err)
+		}
+		events[i] = event
+	}
+	return events
+}
+
+func countLines(t *testing.T, path string) int {
+	t.Helper()
+
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	lines := 0
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		lines++
+	}
+	return lines
+}
+
+func TestMemoryStoreMaxEvents(t *testing.T) {
+	store := NewMemoryStore()
+	store.Retention = Retention{MaxEvents: 10}
+
+	events := appendEvents(t, store, 100, time.Now())
+
+	page, err := store.Query(EventQuery{})
+	if err != nil {
+		t.Fatalf("Query: %v", err)
+	}
+	if n := len(page.Events); n < 10 || n > 11 {
+		t.Errorf("store kept %d events, want 10 plus at most a tenth", n)
+	}
+	if _, err := store.Get(events[0].ID); !errors.Is(err, ErrEventNotFound) {
+		t.Errorf("oldest event still stored: %v", err)
+	}
+	latest := events[len(events)-1]
+	if got, err := store.Get(latest.ID); err != nil || got.ID != latest.ID {
+		t.Errorf("Get(latest) = %+v, %v", got, err)
+	}
+	if last := page.Events[len(page.Events)-1]; last.ID != latest.ID {
+		t.Errorf("last queried event is %s, want %s", last.ID, latest.ID)
+	}
+
+	// cursors still work after a trim
+	page, err = store.Query(EventQuery{After: events[95].ID})
+	if err != nil || len(page.Events) != 4 {
+		t.Errorf("Query after %s returned %d events, %v; want 4", events[95].ID, len(page.Events), err)
+	}
+}
+
+func TestMemoryStoreMaxAge(t *testing.T) {
+	store := NewMemoryStore()
+	store.Retention = Retention{MaxAge: time.Hour}
+
+	appendEvents(t, store, 20, time.Now().Add(-2*time.Hour))
+	fresh := appendEvents(t, store, 5, time.Now())
+
+	recent, err := store.Recent(100, time.Time{})
+	if err != nil {
+		t.Fatalf("Recent: %v", err)
+	}
+	if len(recent) != len(fresh) || recent[0].ID != fresh[0].ID {
+		t.Errorf("got %d events starting at %s, want the %d fresh ones", len(recent), recent[0].ID, len(fresh))
+	}
+}
+
+func TestRetentionKeepsLatestEvent(t *testing.T) {
+	store := NewMemoryStore()
+	store.Retention = Retention{MaxAge: time.Hour}
+
+	old := appendEvents(t, store, 30, time.Now().Add(-2*time.Hour))
+
+	recent, _ := store.Recent(100, time.Time{})
+	if len(recent) != 1 || recent[0].ID != old[len(old)-1].ID {
+		t.Errorf("got %d events, want only the latest", len(recent))
+	}
+}
+
+func TestFileStoreCompacts(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events.jsonl")
+
+	store, err := OpenFileStore(path, Retention{MaxEvents: 10})
+	if err != nil {
+		t.Fatalf("OpenFileStore: %v", err)
+	}
+	events := appendEvents(t, store, 30, time.Now())
+	if err := store.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if lines := countLines(t, path); lines < 10 || lines > 11 {
+		t.Errorf("file has %d events, want 10 plus at most a tenth", lines)
+	}
+
+	// a tighter limit takes effect on the next start
+	store, err = OpenFileStore(path, Retention{MaxEvents: 5})
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	defer store.Close()
+	if lines := countLines(t, path); lines != 5 {
+		t.Errorf("file has %d events after reopening, want 5", lines)
+	}
+
+	recent, _ := store.Recent(100, time.Time{})
+	if len(recent) != 5 || recent[4].ID != events[29].ID {
+		t.Errorf("loaded %d events, want the latest 5", len(recent))
+	}
+
+	// IDs continue after the kept events, and appends land in the rewritten file
+	event, err := store.Append(testEvent("Peru", time.Now()))
+	if err != nil {
+		t.Fatalf("Append after compaction: %v", err)
+	}
+	if want := strconv.Itoa(len(events) + 1); event.ID != want {
+		t.Errorf("next ID = %s, want %s", event.ID, want)
+	}
+	if lines := countLines(t, path); lines != 6 {
+		t.Errorf("file has %d events after appending, want 6", lines)
+	}
+}
+
+func TestFileStoreSkipsTruncatedLine(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events.jsonl")
+	content := `{"id":"1","timestamp":"2025-01-01T00:00:00Z","country":"Peru"}` + "\n" + `{"id":"2","timest`
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	store, err := OpenFileStore(path, Retention{})
+	if err != nil {
+		t.Fatalf("OpenFileStore: %v", err)
+	}
+	defer store.Close()
+
+	event, err := store.Append(testEvent("Chile", time.Now()))
+	if err != nil {
+		t.Fatalf("Append: %v", err)
+	}
+	if event.ID != "2" {
+		t.Errorf("ID = %s, want 2", event.ID)
+	}
+	if _, err := store.Get("1"); err != nil {
+		t.Errorf("Get(1): %v", err)
+	}
+}
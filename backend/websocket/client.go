@@ -1,8 +1,13 @@
 package websocket
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
 	"log"
 	"net/http"
+	"sync/atomic"
+	"time"
 
 	"github.com/gorilla/websocket"
 )
@@ -17,46 +22,141 @@ var upgrader = websocket.Upgrader{
 
 // represents a WebSocket client connection
 type Client struct {
-	Hub  *Hub
-	Conn *websocket.Conn
-	Send chan Message
+	ID          uint64
+	Hub         *Hub
+	Conn        *websocket.Conn
+	Send        chan Message // closed by the hub when the client is removed
+	ConnectedAt time.Time
+
+	// subscription state, only touched by the hub's goroutine
+	filter       Filter
+	unsubscribed bool
+
+	// set by Resume before the client is registered
+	resume     bool
+	resumeFrom uint64
+
+	// set by the hub before it closes Send
+	closeCode int
+
+	sent    atomic.Int64
+	dropped atomic.Int64
 }
 
+var lastClientID atomic.Uint64
+
 func NewClient(hub *Hub, conn *websocket.Conn) *Client {
+	buffer := hub.Config.SendBuffer
+	if buffer <= 0 {
+		buffer = 256
+	}
+
 	return &Client{
-		Hub:  hub,
-		Conn: conn,
-		Send: make(chan Message, 256),
+		ID:          lastClientID.Add(1),
+		Hub:         hub,
+		Conn:        conn,
+		Send:        make(chan Message, buffer),
+		ConnectedAt: time.Now(),
 	}
 }
 
+// Resume makes the hub send the broadcasts after seq, instead of the snapshot,
+// when the client registers. It must be called before registering.
+func (c *Client) Resume(seq uint64) {
+	c.resume = true
+	c.resumeFrom = seq
+}
+
+// ReadPump reads commands until the connection fails, is closed, or the peer
+// goes quiet for longer than PongWait, then unregisters the client
 func (c *Client) ReadPump() {
 	defer func() {
 		c.Hub.Unregister <- c
 		c.Conn.Close()
 	}()
 
+	config := c.Hub.Config
+	c.Conn.SetReadLimit(config.MaxMessageSize)
+	c.Conn.SetReadDeadline(time.Now().Add(config.PongWait))
+	c.Conn.SetPongHandler(func(string) error {
+		return c.Conn.SetReadDeadline(time.Now().Add(config.PongWait))
+	})
+
 	for {
-		var msg Message
-		err := c.Conn.ReadJSON(&msg)
+		_, data, err := c.Conn.ReadMessage()
 		if err != nil {
-			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
+			if errors.Is(err, websocket.ErrReadLimit) {
+				log.Printf("WebSocket message over %d bytes, closing connection", config.MaxMessageSize)
+			} else if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
 				log.Printf("WebSocket error: %v", err)
 			}
 			break
 		}
-		// For now, we only broadcast from server to clients
+		c.Conn.SetReadDeadline(time.Now().Add(config.PongWait))
+
+		// the hub applies commands so subscription state has a single owner
+		var command Command
+		if err := json.Unmarshal(data, &command); err != nil {
+			command = Command{}
+		}
+		c.Hub.Commands <- ClientCommand{Client: c, Command: command}
+	}
+}
+
+// apply runs a command and returns its ack; called by the hub
+func (c *Client) apply(command Command) AckData {
+	ack := AckData{ID: command.ID, Command: command.Type, OK: true}
+
+	switch command.Type {
+	case CommandSubscribe:
+		filter := Filter{}
+		if command.Filter != nil {
+			filter = *command.Filter
+		}
+		if err := filter.Validate(); err != nil {
+			ack.OK = false
+			ack.Error = err.Error()
+			return ack
+		}
+		c.filter = filter
+		c.unsubscribed = false
+
+	case CommandUnsubscribe:
+		c.unsubscribed = true
+
+	default:
+		ack.OK = false
+		ack.Error = fmt.Sprintf("unknown command %q", command.Type)
+	}
+	return ack
+}
+
+// wants reports whether a broadcast message should go to this client; called by the hub
+func (c *Client) wants(message Message) bool {
+	if _, ok := message.Data.(EmotionData); ok && c.unsubscribed {
+		return false
 	}
+	return c.filter.Matches(message)
 }
 
+// WritePump sends queued messages and pings the peer every PingInterval.
+// A write that takes longer than WriteWait closes the connection, which
+// ends ReadPump and unregisters the client.
 func (c *Client) WritePump() {
-	defer c.Conn.Close()
+	config := c.Hub.Config
+	ticker := time.NewTicker(config.PingInterval)
+	defer func() {
+		ticker.Stop()
+		c.Conn.Close()
+	}()
 
 	for {
 		select {
 		case message, ok := <-c.Send:
+			c.Conn.SetWriteDeadline(time.Now().Add(config.WriteWait))
 			if !ok {
-				c.Conn.WriteMessage(websocket.CloseMessage, []byte{})
+				// the hub removed the client; closeCode is set before Send is closed
+				c.Conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(c.closeCode, closeReason(c.closeCode)))
 				return
 			}
 
@@ -65,6 +165,35 @@ func (c *Client) WritePump() {
 				log.Printf("WebSocket write error: %v", err)
 				return
 			}
+			c.sent.Add(1)
+
+		case <-ticker.C:
+			c.Conn.SetWriteDeadline(time.Now().Add(config.WriteWait))
+			if err := c.Conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				return
+			}
 		}
 	}
 }
+
+// stats reports the client's counters; called by the hub
+func (c *Client) stats() ClientStats {
+	stats := ClientStats{
+		ID:          c.ID,
+		ConnectedAt: c.ConnectedAt,
+		Queued:      len(c.Send),
+		Sent:        c.sent.Load(),
+		Dropped:     c.dropped.Load(),
+	}
+	if c.Conn != nil {
+		stats.Remote = c.Conn.RemoteAddr().String()
+	}
+	return stats
+}
+
+func closeReason(code int) string {
+	if code == websocket.CloseTryAgainLater {
+		return "client too slow"
+	}
+	return ""
+}
@@ -0,0 +1,278 @@
+package websocket
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// newTestHub runs a hub with the given config
+func newTestHub(t *testing.T, config Config) *Hub {
+	t.Helper()
+
+	hub := NewHub()
+	hub.Config = config
+	go hub.Run()
+	return hub
+}
+
+func testConfig() Config {
+	return Config{
+		WriteWait:          time.Second,
+		PongWait:           200 * time.Millisecond,
+		PingInterval:       50 * time.Millisecond,
+		MaxMessageSize:     512,
+		SendBuffer:         16,
+		SlowConsumerPolicy: SlowConsumerDisconnect,
+		ReplayBuffer:       16,
+	}
+}
+
+// dialTestServer serves hub over a gorilla test server and connects a peer to it
+func dialTestServer(t *testing.T, hub *Hub) *websocket.Conn {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		client := NewClient(hub, conn)
+		hub.Register <- client
+		go client.WritePump()
+		go client.ReadPump()
+	}))
+	t.Cleanup(server.Close)
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), nil)
+	if err != nil {
+		t.Fatalf("Dial: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	waitFor(t, time.Second, func() bool { return hub.Stats().Clients == 1 })
+	return conn
+}
+
+// waitFor polls cond until it holds or timeout passes
+func waitFor(t *testing.T, timeout time.Duration, cond func() bool) {
+	t.Helper()
+
+	deadline := time.Now().Add(timeout)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not met in time")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestStalledPeerIsUnregistered(t *testing.T) {
+	config := testConfig()
+	hub := newTestHub(t, config)
+	conn := dialTestServer(t, hub)
+
+	// never read, so pings go unanswered
+	conn.SetPingHandler(func(string) error { return nil })
+
+	start := time.Now()
+	waitFor(t, 5*config.PongWait, func() bool { return hub.Stats().Clients == 0 })
+	if elapsed := time.Since(start); elapsed < config.PongWait/2 {
+		t.Errorf("peer removed after %v, before PongWait %v", elapsed, config.PongWait)
+	}
+}
+
+func TestResponsivePeerStaysConnected(t *testing.T) {
+	config := testConfig()
+	hub := newTestHub(t, config)
+	conn := dialTestServer(t, hub)
+
+	// reading answers pings with pongs
+	go func() {
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	time.Sleep(3 * config.PongWait)
+	if clients := hub.Stats().Clients; clients != 1 {
+		t.Errorf("responsive peer was disconnected, %d clients left", clients)
+	}
+}
+
+func TestOversizedFrameClosesConnection(t *testing.T) {
+	config := testConfig()
+	hub := newTestHub(t, config)
+	conn := dialTestServer(t, hub)
+
+	frame := strings.Repeat("x", int(config.MaxMessageSize)+1)
+	if err := conn.WriteMessage(websocket.TextMessage, []byte(frame)); err != nil {
+		t.Fatalf("WriteMessage: %v", err)
+	}
+
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	for {
+		_, _, err := conn.ReadMessage()
+		if err == nil {
+			continue
+		}
+		var closeErr *websocket.CloseError
+		if !errors.As(err, &closeErr) || closeErr.Code != websocket.CloseMessageTooBig {
+			t.Fatalf("read error = %v, want close code %d", err, websocket.CloseMessageTooBig)
+		}
+		break
+	}
+
+	waitFor(t, time.Second, func() bool { return hub.Stats().Clients == 0 })
+}
+
+func TestConfigClampsPingInterval(t *testing.T) {
+	tests := []struct {
+		pingInterval string
+		want         time.Duration
+	}{
+		{"2s", 900 * time.Millisecond},    // above WS_PONG_WAIT
+		{"1s", 900 * time.Millisecond},    // equal to WS_PONG_WAIT
+		{"500ms", 500 * time.Millisecond}, // valid
+		{"", 900 * time.Millisecond},      // unset
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.pingInterval, func(t *testing.T) {
+			t.Setenv("WS_PONG_WAIT", "1s")
+			t.Setenv("WS_PING_INTERVAL", tt.pingInterval)
+
+			config := NewConfigFromEnv()
+			if config.PingInterval != tt.want {
+				t.Errorf("PingInterval = %v, want %v", config.PingInterval, tt.want)
+			}
+			if config.PingInterval >= config.PongWait {
+				t.Errorf("PingInterval %v not below PongWait %v", config.PingInterval, config.PongWait)
+			}
+		})
+	}
+}
+
+// received is a message as a peer decodes it
+type received struct {
+	Type string          `json:"type"`
+	Seq  uint64          `json:"seq"`
+	Data json.RawMessage `json:"data"`
+}
+
+// readMessage reads the next message, failing the test if none comes
+func readMessage(t *testing.T, conn *websocket.Conn) received {
+	t.Helper()
+
+	var message received
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	if err := conn.ReadJSON(&message); err != nil {
+		t.Fatalf("ReadJSON: %v", err)
+	}
+	return message
+}
+
+// sendCommand writes raw to the server and returns the ack it gets back
+func sendCommand(t *testing.T, conn *websocket.Conn, raw string) AckData {
+	t.Helper()
+
+	if err := conn.WriteMessage(websocket.TextMessage, []byte(raw)); err != nil {
+		t.Fatalf("WriteMessage: %v", err)
+	}
+	message := readMessage(t, conn)
+	if message.Type != MessageTypeAck {
+		t.Fatalf("got a %s message, want an ack", message.Type)
+	}
+	var ack AckData
+	if err := json.Unmarshal(message.Data, &ack); err != nil {
+		t.Fatalf("ack: %v", err)
+	}
+	return ack
+}
+
+func TestClientCommands(t *testing.T) {
+	config := testConfig()
+	config.PongWait = 5 * time.Second
+	config.PingInterval = time.Second
+	hub := newTestHub(t, config)
+	conn := dialTestServer(t, hub)
+
+	// expectEmotions broadcasts every point followed by a marker, and checks
+	// the peer receives exactly the wanted countries before the marker
+	expectEmotions := func(points []EmotionData, want ...string) {
+		t.Helper()
+
+		for _, data := range points {
+			hub.Broadcast <- Message{Type: MessageTypeEmotion, Data: data}
+		}
+		hub.Broadcast <- Message{Type: MessageTypeInfo, Data: QuotaData{Kind: InfoKindQuota}}
+
+		var got []string
+		for {
+			message := readMessage(t, conn)
+			if message.Type == MessageTypeInfo {
+				break
+			}
+			var data EmotionData
+			if err := json.Unmarshal(message.Data, &data); err != nil {
+				t.Fatalf("emotion: %v", err)
+			}
+			got = append(got, data.Country)
+		}
+		if strings.Join(got, ",") != strings.Join(want, ",") {
+			t.Errorf("received %v, want %v", got, want)
+		}
+	}
+
+	points := []EmotionData{
+		{Country: "Bolivia", CountryCode: "bo", Emotion: "joy", Intensity: 0.8},
+		{Country: "Peru", CountryCode: "pe", Emotion: "joy", Intensity: 0.8},
+		{Country: "Chile", CountryCode: "cl", Emotion: "joy", Intensity: 0.2},
+	}
+
+	// clients start out receiving everything
+	expectEmotions(points, "Bolivia", "Peru", "Chile")
+
+	ack := sendCommand(t, conn, `{"type":"subscribe","id":"1","filter":{"country":["bo","Chile"],"min_intensity":0.5}}`)
+	if ack != (AckData{ID: "1", Command: CommandSubscribe, OK: true}) {
+		t.Errorf("subscribe ack = %+v", ack)
+	}
+	expectEmotions(points, "Bolivia")
+
+	// an invalid filter is rejected and the previous one kept
+	ack = sendCommand(t, conn, `{"type":"subscribe","id":"2","filter":{"bbox":[1,2,3]}}`)
+	if ack.ID != "2" || ack.OK || ack.Error == "" {
+		t.Errorf("invalid subscribe ack = %+v", ack)
+	}
+	expectEmotions(points, "Bolivia")
+
+	ack = sendCommand(t, conn, `{"type":"unsubscribe","id":"3"}`)
+	if ack != (AckData{ID: "3", Command: CommandUnsubscribe, OK: true}) {
+		t.Errorf("unsubscribe ack = %+v", ack)
+	}
+	expectEmotions(points) // info messages still arrive
+
+	ack = sendCommand(t, conn, `{"type":"pause","id":"4"}`)
+	if ack.ID != "4" || ack.OK || !strings.Contains(ack.Error, "pause") {
+		t.Errorf("unknown command ack = %+v", ack)
+	}
+	ack = sendCommand(t, conn, `not json`)
+	if ack.OK || ack.Error == "" {
+		t.Errorf("malformed command ack = %+v", ack)
+	}
+
+	// subscribing without a filter resumes everything
+	ack = sendCommand(t, conn, `{"type":"subscribe","id":"5"}`)
+	if !ack.OK {
+		t.Errorf("subscribe ack = %+v", ack)
+	}
+	expectEmotions(points, "Bolivia", "Peru", "Chile")
+}
@@ -0,0 +1,101 @@
+package websocket
+
+import (
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Config controls connection keepalive and limits
+type Config struct {
+	// WriteWait is how long a write may take before the peer is considered gone
+	WriteWait time.Duration
+
+	// PongWait is how long to wait for any frame, including pongs, from the peer
+	PongWait time.Duration
+
+	// PingInterval is how often to ping the peer; must be less than PongWait
+	PingInterval time.Duration
+
+	// MaxMessageSize is the largest inbound frame accepted, in bytes
+	MaxMessageSize int64
+
+	// SendBuffer is how many outbound messages a client can fall behind by
+	SendBuffer int
+
+	// SlowConsumerPolicy is what happens when a client's send buffer is full
+	SlowConsumerPolicy string
+
+	// ReplayBuffer is how many broadcasts are kept for clients resuming with a
+	// sequence number; 0 disables resuming
+	ReplayBuffer int
+}
+
+// Slow-consumer policies
+const (
+	SlowConsumerDisconnect = "disconnect"  // close the connection with code 1013 (try again later)
+	SlowConsumerDropOldest = "drop-oldest" // discard the oldest queued message to make room
+	SlowConsumerDropNewest = "drop-newest" // discard the new message
+)
+
+// NewConfigFromEnv reads WS_WRITE_WAIT, WS_PONG_WAIT, WS_PING_INTERVAL,
+// WS_MAX_MESSAGE_SIZE, WS_SEND_BUFFER, WS_SLOW_CONSUMER and WS_REPLAY_BUFFER
+func NewConfigFromEnv() Config {
+	config := Config{
+		WriteWait:          10 * time.Second,
+		PongWait:           60 * time.Second,
+		MaxMessageSize:     4096,
+		SendBuffer:         256,
+		SlowConsumerPolicy: SlowConsumerDisconnect,
+		ReplayBuffer:       1000,
+	}
+
+	if env := os.Getenv("WS_WRITE_WAIT"); env != "" {
+		if parsed, err := time.ParseDuration(env); err == nil && parsed > 0 {
+			config.WriteWait = parsed
+		}
+	}
+	if env := os.Getenv("WS_PONG_WAIT"); env != "" {
+		if parsed, err := time.ParseDuration(env); err == nil && parsed > 0 {
+			config.PongWait = parsed
+		}
+	}
+	if env := os.Getenv("WS_PING_INTERVAL"); env != "" {
+		if parsed, err := time.ParseDuration(env); err == nil && parsed > 0 {
+			config.PingInterval = parsed
+		}
+	}
+	if env := os.Getenv("WS_MAX_MESSAGE_SIZE"); env != "" {
+		if parsed, err := strconv.ParseInt(env, 10, 64); err == nil && parsed > 0 {
+			config.MaxMessageSize = parsed
+		}
+	}
+	if env := os.Getenv("WS_SEND_BUFFER"); env != "" {
+		if parsed, err := strconv.Atoi(env); err == nil && parsed > 0 {
+			config.SendBuffer = parsed
+		}
+	}
+	if env := os.Getenv("WS_REPLAY_BUFFER"); env != "" {
+		if parsed, err := strconv.Atoi(env); err == nil && parsed >= 0 {
+			config.ReplayBuffer = parsed
+		}
+	}
+	switch policy := strings.ToLower(os.Getenv("WS_SLOW_CONSUMER")); policy {
+	case "":
+	case SlowConsumerDisconnect, SlowConsumerDropOldest, SlowConsumerDropNewest:
+		config.SlowConsumerPolicy = policy
+	default:
+		log.Printf("Unknown WS_SLOW_CONSUMER %q, using %s", policy, SlowConsumerDisconnect)
+	}
+
+	// pings have to arrive before the peer's read deadline does
+	if config.PingInterval <= 0 || config.PingInterval >= config.PongWait {
+		if config.PingInterval > 0 {
+			log.Printf("WS_PING_INTERVAL %v is not below WS_PONG_WAIT %v, using %v", config.PingInterval, config.PongWait, config.PongWait*9/10)
+		}
+		config.PingInterval = config.PongWait * 9 / 10
+	}
+	return config
+}
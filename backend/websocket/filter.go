@@ -0,0 +1,77 @@
+package websocket
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Filter selects the emotion messages a client receives. Empty fields match
+// everything; a zero Filter matches every message.
+type Filter struct {
+	Countries    []string  `json:"country,omitempty"` // ISO codes or names
+	Emotions     []string  `json:"emotion,omitempty"` // top labels
+	MinIntensity float64   `json:"min_intensity,omitempty"`
+	BBox         []float64 `json:"bbox,omitempty"` // minLng, minLat, maxLng, maxLat
+}
+
+// Validate checks the filter is well formed
+func (f Filter) Validate() error {
+	if len(f.BBox) != 0 && len(f.BBox) != 4 {
+		return fmt.Errorf("bbox must be [minLng, minLat, maxLng, maxLat]")
+	}
+	if len(f.BBox) == 4 && f.BBox[1] > f.BBox[3] {
+		return fmt.Errorf("bbox minLat is above maxLat")
+	}
+	if f.MinIntensity < 0 || f.MinIntensity > 1 {
+		return fmt.Errorf("min_intensity must be between 0 and 1")
+	}
+	return nil
+}
+
+// Matches reports whether a message passes the filter. Only emotion data is
+// filtered; every other message (info, errors, ...) always passes.
+func (f Filter) Matches(message Message) bool {
+	data, ok := message.Data.(EmotionData)
+	if !ok {
+		return true
+	}
+
+	if len(f.Countries) > 0 && !containsFold(f.Countries, data.Country) && !containsFold(f.Countries, data.CountryCode) {
+		return false
+	}
+	if len(f.Emotions) > 0 && !containsFold(f.Emotions, data.Emotion) {
+		return false
+	}
+	if data.Intensity < f.MinIntensity {
+		return false
+	}
+	if len(f.BBox) == 4 && !inBBox(f.BBox, data.Lat, data.Lng) {
+		return false
+	}
+	return true
+}
+
+func containsFold(values []string, value string) bool {
+	if value == "" {
+		return false
+	}
+	for _, v := range values {
+		if strings.EqualFold(strings.TrimSpace(v), value) {
+			return true
+		}
+	}
+	return false
+}
+
+// inBBox checks a point against minLng, minLat, maxLng, maxLat; a box with
+// minLng > maxLng crosses the antimeridian
+func inBBox(bbox []float64, lat, lng float64) bool {
+	minLng, minLat, maxLng, maxLat := bbox[0], bbox[1], bbox[2], bbox[3]
+	if lat < minLat || lat > maxLat {
+		return false
+	}
+	if minLng > maxLng {
+		return lng >= minLng || lng <= maxLng
+	}
+	return lng >= minLng && lng <= maxLng
+}
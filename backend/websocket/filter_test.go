@@ -0,0 +1,83 @@
+package websocket
+
+import "testing"
+
+func TestFilterValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		filter  Filter
+		wantErr bool
+	}{
+		{"empty", Filter{}, false},
+		{"bbox", Filter{BBox: []float64{-70, -23, -57, -9}}, false},
+		{"bbox across the antimeridian", Filter{BBox: []float64{170, -50, -170, -30}}, false},
+		{"bbox too short", Filter{BBox: []float64{1, 2, 3}}, true},
+		{"bbox upside down", Filter{BBox: []float64{-70, 10, -57, -9}}, true},
+		{"min_intensity", Filter{MinIntensity: 1}, false},
+		{"min_intensity negative", Filter{MinIntensity: -0.1}, true},
+		{"min_intensity above 1", Filter{MinIntensity: 1.5}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.filter.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() = %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestFilterMatches(t *testing.T) {
+	laPaz := EmotionData{Country: "Bolivia", CountryCode: "bo", Emotion: "joy", Intensity: 0.6, Lat: -16.5, Lng: -68.15}
+	fiji := EmotionData{Country: "Fiji", CountryCode: "fj", Emotion: "sadness", Intensity: 0.3, Lat: -18.1, Lng: 178.4}
+	samoa := EmotionData{Country: "Samoa", CountryCode: "ws", Emotion: "joy", Intensity: 0.9, Lat: -13.8, Lng: -171.8}
+
+	tests := []struct {
+		name   string
+		filter Filter
+		data   EmotionData
+		want   bool
+	}{
+		{"zero filter", Filter{}, laPaz, true},
+
+		// countries match either the name or the code
+		{"country name", Filter{Countries: []string{"bolivia"}}, laPaz, true},
+		{"country code", Filter{Countries: []string{"BO"}}, laPaz, true},
+		{"country padded", Filter{Countries: []string{" bo "}}, laPaz, true},
+		{"other country", Filter{Countries: []string{"pe", "Chile"}}, laPaz, false},
+		{"empty code", Filter{Countries: []string{""}}, EmotionData{Country: "Bolivia"}, false},
+
+		{"emotion", Filter{Emotions: []string{"Joy", "love"}}, laPaz, true},
+		{"other emotion", Filter{Emotions: []string{"anger"}}, laPaz, false},
+
+		{"min_intensity met", Filter{MinIntensity: 0.6}, laPaz, true},
+		{"min_intensity missed", Filter{MinIntensity: 0.7}, laPaz, false},
+
+		{"in bbox", Filter{BBox: []float64{-70, -23, -57, -9}}, laPaz, true},
+		{"outside bbox", Filter{BBox: []float64{-70, -10, -57, 0}}, laPaz, false},
+
+		// a box from 170 to -175 crosses the antimeridian
+		{"antimeridian east of it", Filter{BBox: []float64{170, -30, -175, -10}}, fiji, true},
+		{"antimeridian west of it", Filter{BBox: []float64{170, -30, -175, -10}}, EmotionData{Lat: -20, Lng: -178}, true},
+		{"antimeridian past the west edge", Filter{BBox: []float64{170, -30, -175, -10}}, samoa, false},
+		{"antimeridian on the west edge", Filter{BBox: []float64{170, -30, -171.8, -10}}, samoa, true},
+		{"antimeridian far away", Filter{BBox: []float64{170, -30, -175, -10}}, laPaz, false},
+
+		{"every field", Filter{Countries: []string{"ws"}, Emotions: []string{"joy"}, MinIntensity: 0.5, BBox: []float64{170, -30, -171, -10}}, samoa, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			message := Message{Type: MessageTypeEmotion, Data: tt.data}
+			if got := tt.filter.Matches(message); got != tt.want {
+				t.Errorf("Matches() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	// only emotion data is filtered
+	filter := Filter{Countries: []string{"bo"}, MinIntensity: 1}
+	if !filter.Matches(Message{Type: MessageTypeInfo, Data: QuotaData{Kind: InfoKindQuota}}) {
+		t.Error("info message filtered out")
+	}
+}
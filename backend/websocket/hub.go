@@ -1,7 +1,13 @@
 package websocket
 
 import (
+	"cmp"
 	"log"
+	"slices"
+	"sync/atomic"
+	"time"
+
+	"github.com/gorilla/websocket"
 )
 
 // Hub maintains the set of active clients and broadcasts messages to the clients
@@ -17,6 +23,29 @@ type Hub struct {
 
 	// Unregister requests from clients
 	Unregister chan *Client
+
+	// Commands sent by clients (subscribe, unsubscribe)
+	Commands chan ClientCommand
+
+	// Config holds keepalive settings and limits for client connections.
+	// Set it before calling Run.
+	Config Config
+
+	// stats requests, answered by Run since it owns Clients
+	stats chan chan HubStats
+
+	// disconnected counts clients removed for falling behind
+	disconnected atomic.Int64
+
+	// seq is the latest broadcast's sequence number. It starts at the hub's
+	// creation time in microseconds, so numbers keep increasing across restarts
+	// and a client resuming from before a restart gets a reset.
+	seq    uint64
+	replay *replayBuffer
+
+	// Snapshot returns the history sent to clients when they connect; nil sends none.
+	// Set it before calling Run.
+	Snapshot func() []EmotionData
 }
 
 // NewHub creates a new Hub
@@ -26,33 +55,225 @@ func NewHub() *Hub {
 		Broadcast:  make(chan Message),
 		Register:   make(chan *Client),
 		Unregister: make(chan *Client),
+		Commands:   make(chan ClientCommand),
+		Config:     NewConfigFromEnv(),
+		stats:      make(chan chan HubStats),
+		seq:        uint64(time.Now().UnixMicro()),
 	}
 }
 
 func (h *Hub) Run() {
+	h.replay = newReplayBuffer(h.Config.ReplayBuffer)
+
 	for {
 		select {
 		case client := <-h.Register:
+			h.catchUp(client)
 			h.Clients[client] = true
 			log.Printf("Client connected. Total clients: %d", len(h.Clients))
 
 		case client := <-h.Unregister:
-			if _, ok := h.Clients[client]; ok {
-				delete(h.Clients, client)
-				close(client.Send)
-				log.Printf("Client disconnected. Total clients: %d", len(h.Clients))
+			h.remove(client, websocket.CloseNormalClosure)
+
+		case command := <-h.Commands:
+			if _, ok := h.Clients[command.Client]; ok {
+				h.send(command.Client, h.stamp(Message{Type: MessageTypeAck, Data: command.Client.apply(command.Command)}))
 			}
 
 		case message := <-h.Broadcast:
-			// Broadcast message to all clients
+			h.seq++
+			message.Seq = h.seq
+			message.Time = time.Now().UTC()
+			h.replay.add(message)
+
+			// Broadcast message to subscribed clients
 			for client := range h.Clients {
-				select {
-				case client.Send <- message:
-				default:
-					close(client.Send)
-					delete(h.Clients, client)
+				if client.wants(message) {
+					h.send(client, message)
 				}
 			}
+
+		case reply := <-h.stats:
+			reply <- h.collectStats()
 		}
 	}
 }
+
+// remove unregisters a client and closes its Send channel, which tells
+// WritePump to close the connection with closeCode. It is the only place Send
+// is closed and does nothing for clients already removed, so a client can be
+// removed by both the slow-consumer policy and its own ReadPump safely.
+func (h *Hub) remove(client *Client, closeCode int) {
+	if _, ok := h.Clients[client]; !ok {
+		return
+	}
+	delete(h.Clients, client)
+	client.closeCode = closeCode
+	close(client.Send)
+	log.Printf("Client disconnected. Total clients: %d", len(h.Clients))
+}
+
+// ClientCommand is a Command together with the client that sent it
+type ClientCommand struct {
+	Client  *Client
+	Command Command
+}
+
+// send queues a message for a client. When the client's buffer is full the
+// slow-consumer policy decides what gives.
+func (h *Hub) send(client *Client, message Message) {
+	select {
+	case client.Send <- message:
+		return
+	default:
+	}
+
+	switch h.Config.SlowConsumerPolicy {
+	case SlowConsumerDropOldest:
+		// make room; WritePump may have taken a message meanwhile, which is fine
+		select {
+		case <-client.Send:
+			client.dropped.Add(1)
+		default:
+		}
+		select {
+		case client.Send <- message:
+		default:
+			client.dropped.Add(1)
+		}
+
+	case SlowConsumerDropNewest:
+		client.dropped.Add(1)
+
+	default:
+		log.Printf("Client %d can't keep up, disconnecting", client.ID)
+		h.disconnected.Add(1)
+		h.remove(client, websocket.CloseTryAgainLater)
+	}
+}
+
+// HubStats is exposed for monitoring
+type HubStats struct {
+	Clients      int           `json:"clients"`
+	Policy       string        `json:"slow_consumer_policy"`
+	Disconnected int64         `json:"disconnected"` // clients removed for falling behind
+	PerClient    []ClientStats `json:"per_client"`
+}
+
+// ClientStats reports one client's delivery counters
+type ClientStats struct {
+	ID          uint64    `json:"id"`
+	Remote      string    `json:"remote,omitempty"`
+	ConnectedAt time.Time `json:"connected_at"`
+	Queued      int       `json:"queued"`  // messages waiting in the send buffer
+	Sent        int64     `json:"sent"`    // messages written to the connection
+	Dropped     int64     `json:"dropped"` // messages discarded by the slow-consumer policy
+}
+
+// Stats reports connected clients and their counters. It must not be called
+// from the hub's own goroutine.
+func (h *Hub) Stats() HubStats {
+	reply := make(chan HubStats, 1)
+	h.stats <- reply
+	return <-reply
+}
+
+func (h *Hub) collectStats() HubStats {
+	stats := HubStats{
+		Clients:      len(h.Clients),
+		Policy:       h.Config.SlowConsumerPolicy,
+		Disconnected: h.disconnected.Load(),
+		PerClient:    make([]ClientStats, 0, len(h.Clients)),
+	}
+	for client := range h.Clients {
+		stats.PerClient = append(stats.PerClient, client.stats())
+	}
+	slices.SortFunc(stats.PerClient, func(a, b ClientStats) int {
+		return cmp.Compare(a.ID, b.ID)
+	})
+	return stats
+}
+
+// stamp gives a message for a single client the latest sequence number
+func (h *Hub) stamp(message Message) Message {
+	message.Seq = h.seq
+	message.Time = time.Now().UTC()
+	return message
+}
+
+// catchUp sends a new client what it missed when it resumes from a sequence
+// number, or the snapshot otherwise. A client that missed more than is
+// buffered, or more than fits in its send buffer, gets a reset and the snapshot.
+func (h *Hub) catchUp(client *Client) {
+	if !client.resume {
+		h.sendSnapshot(client)
+		return
+	}
+
+	missed, ok := h.missedSince(client)
+	if !ok {
+		oldest, _ := h.replay.oldest()
+		reset := ResetData{Since: client.resumeFrom, Oldest: oldest, Latest: h.seq}
+		client.Send <- h.stamp(Message{Type: MessageTypeReset, Data: reset})
+		h.sendSnapshot(client)
+		return
+	}
+
+	for _, message := range missed {
+		client.Send <- message
+	}
+}
+
+// missedSince returns the buffered broadcasts after the client's resume point
+// that pass its filter, or false when some of them are no longer buffered
+func (h *Hub) missedSince(client *Client) ([]Message, bool) {
+	since := client.resumeFrom
+	if since == h.seq {
+		return nil, true
+	}
+	if since > h.seq {
+		// from a previous run of a server whose clock has gone backwards
+		return nil, false
+	}
+
+	oldest, ok := h.replay.oldest()
+	if !ok || oldest > since+1 {
+		return nil, false
+	}
+
+	var missed []Message
+	for _, message := range h.replay.after(since) {
+		if client.wants(message) {
+			missed = append(missed, message)
+		}
+	}
+	// leave room for live messages and the reset notice
+	if len(missed) > cap(client.Send)-1 {
+		return nil, false
+	}
+	return missed, true
+}
+
+// sendSnapshot queues the history for a new client ahead of any live message,
+// keeping only events that pass the client's filter
+func (h *Hub) sendSnapshot(client *Client) {
+	if h.Snapshot == nil {
+		return
+	}
+
+	var events []EmotionData
+	for _, data := range h.Snapshot() {
+		if client.wants(Message{Type: MessageTypeEmotion, Data: data}) {
+			events = append(events, data)
+		}
+	}
+	if len(events) == 0 {
+		return
+	}
+
+	select {
+	case client.Send <- h.stamp(Message{Type: MessageTypeSnapshot, Data: events}):
+	default:
+		log.Printf("Client send buffer full, skipping snapshot")
+	}
+}
@@ -0,0 +1,309 @@
+package websocket
+
+import (
+	"math"
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/gorilla/websocket"
+)
+
+// registerIdle registers n clients without pumps, so nothing drains their buffers
+func registerIdle(hub *Hub, n int) []*Client {
+	clients := make([]*Client, n)
+	for i := range clients {
+		clients[i] = NewClient(hub, nil)
+		hub.Register <- clients[i]
+	}
+	return clients
+}
+
+func broadcast(hub *Hub, n int) {
+	for i := 0; i < n; i++ {
+		hub.Broadcast <- Message{Type: MessageTypeEmotion, Data: EmotionData{Country: "Bolivia", Intensity: 0.5}}
+	}
+}
+
+// drain returns the sequence numbers left in a client's buffer
+func drain(client *Client) []uint64 {
+	var seqs []uint64
+	for _, message := range pending(client) {
+		seqs = append(seqs, message.Seq)
+	}
+	return seqs
+}
+
+// pending returns the messages left in a client's buffer
+func pending(client *Client) []Message {
+	var messages []Message
+	for {
+		select {
+		case message, ok := <-client.Send:
+			if !ok {
+				return messages
+			}
+			messages = append(messages, message)
+		default:
+			return messages
+		}
+	}
+}
+
+func TestSlowConsumerPolicies(t *testing.T) {
+	const (
+		clients    = 50
+		buffer     = 4
+		broadcasts = 10
+	)
+
+	tests := []struct {
+		policy       string
+		clients      int   // still connected afterwards
+		dropped      int64 // per client
+		disconnected int64
+		keepsNewest  bool // whether the buffer holds the latest messages
+	}{
+		{SlowConsumerDisconnect, 0, 0, clients, false},
+		{SlowConsumerDropOldest, clients, broadcasts - buffer, 0, true},
+		{SlowConsumerDropNewest, clients, broadcasts - buffer, 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.policy, func(t *testing.T) {
+			config := testConfig()
+			config.SendBuffer = buffer
+			config.SlowConsumerPolicy = tt.policy
+			hub := newTestHub(t, config)
+
+			idle := registerIdle(hub, clients)
+			broadcast(hub, broadcasts)
+
+			// Stats goes through the hub's loop, so every broadcast has been handled
+			stats := hub.Stats()
+			if stats.Clients != tt.clients {
+				t.Errorf("Clients = %d, want %d", stats.Clients, tt.clients)
+			}
+			if stats.Disconnected != tt.disconnected {
+				t.Errorf("Disconnected = %d, want %d", stats.Disconnected, tt.disconnected)
+			}
+			for _, client := range stats.PerClient {
+				if client.Dropped != tt.dropped {
+					t.Errorf("client %d dropped %d, want %d", client.ID, client.Dropped, tt.dropped)
+				}
+				if client.Queued != buffer {
+					t.Errorf("client %d has %d queued, want %d", client.ID, client.Queued, buffer)
+				}
+			}
+
+			for _, client := range idle {
+				seqs := drain(client)
+				if len(seqs) != buffer {
+					t.Fatalf("client %d buffered %d messages, want %d", client.ID, len(seqs), buffer)
+				}
+				first, last := seqs[0], seqs[len(seqs)-1]
+				if last-first != buffer-1 {
+					t.Errorf("client %d buffered non-consecutive messages %v", client.ID, seqs)
+				}
+				if tt.keepsNewest && last != hub.seq {
+					t.Errorf("client %d kept %v, want the latest messages up to %d", client.ID, seqs, hub.seq)
+				}
+				if !tt.keepsNewest && last == hub.seq {
+					t.Errorf("client %d kept %v, want the first messages", client.ID, seqs)
+				}
+			}
+
+			if tt.policy == SlowConsumerDisconnect {
+				for _, client := range idle {
+					if _, ok := <-client.Send; ok {
+						t.Fatalf("client %d: Send still open after disconnect", client.ID)
+					}
+					if client.closeCode != websocket.CloseTryAgainLater {
+						t.Errorf("client %d closed with %d, want %d", client.ID, client.closeCode, websocket.CloseTryAgainLater)
+					}
+				}
+			}
+
+			// each client's pump unregisters it when it stops; a client the
+			// policy already removed must not be closed twice
+			for _, client := range idle {
+				hub.Unregister <- client
+			}
+			if stats := hub.Stats(); stats.Clients != 0 {
+				t.Errorf("%d clients left after unregistering all", stats.Clients)
+			}
+		})
+	}
+}
+
+// TestHubConcurrentRemoval races slow-consumer removal against clients
+// unregistering themselves, as their pumps do when a connection ends
+func TestHubConcurrentRemoval(t *testing.T) {
+	const clients = 200
+
+	config := testConfig()
+	config.SendBuffer = 1
+	hub := newTestHub(t, config)
+
+	var wg sync.WaitGroup
+	for i := 0; i < clients; i++ {
+		client := NewClient(hub, nil)
+		hub.Register <- client
+
+		wg.Add(1)
+		go func(n int) {
+			defer wg.Done()
+			// read a few messages like a WritePump, then go away like a ReadPump
+			for read := 0; read < n%5; read++ {
+				if _, ok := <-client.Send; !ok {
+					break
+				}
+			}
+			hub.Unregister <- client
+			hub.Unregister <- client // a second unregister must be harmless
+			for range client.Send {
+			}
+		}(i)
+	}
+
+	var broadcasters sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		broadcasters.Add(1)
+		go func() {
+			defer broadcasters.Done()
+			broadcast(hub, 50)
+		}()
+	}
+
+	broadcasters.Wait()
+	wg.Wait()
+
+	if stats := hub.Stats(); stats.Clients != 0 {
+		t.Errorf("%d clients left, want 0", stats.Clients)
+	}
+}
+
+func TestResume(t *testing.T) {
+	const (
+		replay     = 8
+		broadcasts = 10
+	)
+
+	// broadcasts alternate Bolivia and Peru; relative to the latest sequence
+	// number, Bolivia's are -9, -7, -5, -3, -1 and Peru's -8, -6, -4, -2, 0
+	tests := []struct {
+		name       string
+		since      int64 // relative to the latest broadcast, minInt64 for 0
+		countries  []string
+		sendBuffer int
+		want       []int64 // missed broadcasts, relative to the latest
+		reset      bool    // whether the client gets a reset instead
+		snapshot   []string
+	}{
+		{name: "no gap", since: 0, want: nil},
+		{name: "missed a few", since: -3, want: []int64{-2, -1, 0}},
+		{name: "oldest buffered is next", since: -replay, want: []int64{-7, -6, -5, -4, -3, -2, -1, 0}},
+		{name: "gap", since: -replay - 1, reset: true, snapshot: []string{"Bolivia", "Peru"}},
+		{name: "before the first broadcast", since: math.MinInt64, reset: true, snapshot: []string{"Bolivia", "Peru"}},
+		{name: "from the future", since: 1, reset: true, snapshot: []string{"Bolivia", "Peru"}},
+
+		// the missed broadcasts must fit the send buffer with a slot to spare
+		{name: "fits the send buffer", since: -3, sendBuffer: 4, want: []int64{-2, -1, 0}},
+		{name: "over the send buffer", since: -4, sendBuffer: 4, reset: true, snapshot: []string{"Bolivia", "Peru"}},
+
+		// only what passes the filter is replayed, or counted against the buffer
+		{name: "filtered", since: -6, countries: []string{"bo"}, want: []int64{-5, -3, -1}},
+		{name: "filtered fits the send buffer", since: -6, countries: []string{"bo"}, sendBuffer: 4, want: []int64{-5, -3, -1}},
+		{name: "filtered gap", since: math.MinInt64, countries: []string{"pe"}, reset: true, snapshot: []string{"Peru"}},
+
+		// the reset fills a one-message buffer, so the snapshot is skipped rather
+		// than blocking the hub
+		{name: "one-message buffer", since: -1, sendBuffer: 1, reset: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := testConfig()
+			config.ReplayBuffer = replay
+			if tt.sendBuffer > 0 {
+				config.SendBuffer = tt.sendBuffer
+			}
+			hub := NewHub()
+			hub.Config = config
+			hub.Snapshot = func() []EmotionData {
+				return []EmotionData{{Country: "Bolivia", CountryCode: "bo"}, {Country: "Peru", CountryCode: "pe"}}
+			}
+			go hub.Run()
+
+			for i := 0; i < broadcasts; i++ {
+				data := EmotionData{Country: "Bolivia", CountryCode: "bo"}
+				if i%2 == 1 {
+					data = EmotionData{Country: "Peru", CountryCode: "pe"}
+				}
+				hub.Broadcast <- Message{Type: MessageTypeEmotion, Data: data}
+			}
+			hub.Stats() // wait for the broadcasts
+			latest := hub.seq
+
+			since := uint64(0)
+			if tt.since != math.MinInt64 {
+				since = uint64(int64(latest) + tt.since)
+			}
+			client := NewClient(hub, nil)
+			client.filter = Filter{Countries: tt.countries}
+			client.Resume(since)
+			hub.Register <- client
+			hub.Stats() // registration queues the catch-up, and must not block
+
+			messages := pending(client)
+			if tt.reset {
+				checkReset(t, messages, ResetData{Since: since, Oldest: latest - replay + 1, Latest: latest}, tt.snapshot)
+				return
+			}
+
+			var got []int64
+			for _, message := range messages {
+				if message.Type != MessageTypeEmotion {
+					t.Fatalf("got a %s message, want only missed broadcasts", message.Type)
+				}
+				got = append(got, int64(message.Seq)-int64(latest))
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("replayed %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// checkReset checks messages are a reset followed by a snapshot of the given
+// countries, or by nothing when countries is empty
+func checkReset(t *testing.T, messages []Message, want ResetData, countries []string) {
+	t.Helper()
+
+	if len(messages) == 0 || messages[0].Type != MessageTypeReset {
+		t.Fatalf("got %+v, want a reset first", messages)
+	}
+	if reset := messages[0].Data.(ResetData); reset != want {
+		t.Errorf("reset = %+v, want %+v", reset, want)
+	}
+	if messages[0].Seq != want.Latest {
+		t.Errorf("reset has seq %d, want the latest %d", messages[0].Seq, want.Latest)
+	}
+
+	if len(countries) == 0 {
+		if len(messages) != 1 {
+			t.Errorf("got %d messages after the reset, want none", len(messages)-1)
+		}
+		return
+	}
+	if len(messages) != 2 || messages[1].Type != MessageTypeSnapshot {
+		t.Fatalf("got %+v, want a reset and a snapshot", messages)
+	}
+	var got []string
+	for _, data := range messages[1].Data.([]EmotionData) {
+		got = append(got, data.Country)
+	}
+	if !reflect.DeepEqual(got, countries) {
+		t.Errorf("snapshot has %v, want %v", got, countries)
+	}
+}
@@ -1,25 +1,107 @@
 package websocket
 
+import "time"
+
 // represents a WebSocket message
 type Message struct {
-	Type string      `json:"type"`
+	Type string `json:"type"`
+
+	// Seq and Time are stamped by the hub. Broadcasts get the next sequence
+	// number; messages for a single client (snapshot, ack, reset) carry the
+	// latest broadcast's, so resuming from the highest Seq seen never skips anything.
+	Seq  uint64    `json:"seq,omitempty"`
+	Time time.Time `json:"time,omitzero"`
+
 	Data interface{} `json:"data"`
 }
 
 // EmotionData represents emotion data with location
 type EmotionData struct {
-	City      string  `json:"city"`
-	Country   string  `json:"country"`
-	Emotion   string  `json:"emotion"`
-	Intensity float64 `json:"intensity"`
-	Lat       float64 `json:"lat"`
-	Lng       float64 `json:"lng"`
-	Text      string  `json:"text,omitempty"` // Optional: original text
+	// ID is the event's ID in the event store, empty when events aren't recorded
+	ID string `json:"id,omitempty"`
+
+	// ArticleID is shared by every point published for the same article,
+	// e.g. one per country for stories tagged with several countries
+	ArticleID string `json:"article_id,omitempty"`
+
+	City        string  `json:"city"`
+	Region      string  `json:"region,omitempty"` // state or province, when that is the most specific place known
+	Country     string  `json:"country"`
+	CountryCode string  `json:"country_code,omitempty"` // ISO 3166-1 alpha-2, lowercase
+	Precision   string  `json:"precision,omitempty"`    // "city", "region" or "country"
+	Emotion     string  `json:"emotion"`
+	Intensity   float64 `json:"intensity"`
+	Weight      float64 `json:"weight"` // 1, or 1/N when an article's N points share its weight
+	Lat         float64 `json:"lat"`
+	Lng         float64 `json:"lng"`
+	Text        string  `json:"text,omitempty"` // Optional: original text
+
+	// Emotions is the full probability vector (joy, sadness, anger, fear,
+	// surprise, love, neutral, disgust); Emotion and Intensity are its top label
+	Emotions map[string]float64 `json:"emotions,omitempty"`
+}
+
+// QuotaData reports the remaining API budget of a news source (sent as an info message)
+type QuotaData struct {
+	Kind        string    `json:"kind"`
+	Source      string    `json:"source"`
+	Limit       int       `json:"limit"`     // requests per day, 0 when unlimited
+	Used        int       `json:"used"`      // requests made in the current window
+	Remaining   int       `json:"remaining"` // -1 when unlimited
+	ResetAt     time.Time `json:"reset_at"`
+	PausedUntil time.Time `json:"paused_until,omitzero"`
+}
+
+// ResetData tells a resuming client that the messages it missed are no longer
+// buffered (sent as a reset message, followed by a fresh snapshot)
+type ResetData struct {
+	Since  uint64 `json:"since"`  // the sequence number the client asked to resume from
+	Oldest uint64 `json:"oldest"` // oldest sequence number still buffered, 0 if none
+	Latest uint64 `json:"latest"` // latest sequence number broadcast
+}
+
+// Command is a message from a client to the server
+type Command struct {
+	Type   string  `json:"type"`             // CommandSubscribe or CommandUnsubscribe
+	ID     string  `json:"id,omitempty"`     // echoed in the ack so clients can match replies
+	Filter *Filter `json:"filter,omitempty"` // for subscribe; nil means everything
 }
 
+// AckData answers a Command (sent as an ack message)
+type AckData struct {
+	ID      string `json:"id,omitempty"`
+	Command string `json:"command"`
+	OK      bool   `json:"ok"`
+	Error   string `json:"error,omitempty"`
+}
+
+// Commands clients can send
+const (
+	// CommandSubscribe replaces the client's filter. Clients start subscribed
+	// to everything.
+	CommandSubscribe = "subscribe"
+
+	// CommandUnsubscribe stops emotion messages until the next subscribe
+	CommandUnsubscribe = "unsubscribe"
+)
+
+// Info message kinds
+const (
+	InfoKindQuota = "quota"
+)
+
 // Message types
 const (
 	MessageTypeEmotion = "emotion"
 	MessageTypeError   = "error"
 	MessageTypeInfo    = "info"
+	MessageTypeAck     = "ack"
+
+	// MessageTypeSnapshot carries recent history ([]EmotionData, oldest first),
+	// sent once to every client when it connects
+	MessageTypeSnapshot = "snapshot"
+
+	// MessageTypeReset means a client can't resume from the sequence number it
+	// asked for and should drop what it has; a snapshot follows
+	MessageTypeReset = "reset"
 )
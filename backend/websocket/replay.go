@@ -0,0 +1,46 @@
+package websocket
+
+// replayBuffer is a ring of the latest broadcast messages, in sequence order
+type replayBuffer struct {
+	messages []Message
+	start    int // index of the oldest message
+	count    int
+}
+
+func newReplayBuffer(capacity int) *replayBuffer {
+	return &replayBuffer{messages: make([]Message, max(capacity, 0))}
+}
+
+// add stores a message, evicting the oldest when full
+func (b *replayBuffer) add(message Message) {
+	if len(b.messages) == 0 {
+		return
+	}
+	if b.count < len(b.messages) {
+		b.messages[(b.start+b.count)%len(b.messages)] = message
+		b.count++
+		return
+	}
+	b.messages[b.start] = message
+	b.start = (b.start + 1) % len(b.messages)
+}
+
+// oldest returns the sequence number of the oldest message still buffered
+func (b *replayBuffer) oldest() (uint64, bool) {
+	if b.count == 0 {
+		return 0, false
+	}
+	return b.messages[b.start].Seq, true
+}
+
+// after returns the buffered messages with a sequence number above seq
+func (b *replayBuffer) after(seq uint64) []Message {
+	var messages []Message
+	for i := 0; i < b.count; i++ {
+		message := b.messages[(b.start+i)%len(b.messages)]
+		if message.Seq > seq {
+			messages = append(messages, message)
+		}
+	}
+	return messages
+}
@@ -0,0 +1,138 @@
+package websocket
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ParseFilter reads a Filter from query parameters: country and emotion
+// (comma-separated), min_intensity and bbox (minLng,minLat,maxLng,maxLat)
+func ParseFilter(params url.Values) (Filter, error) {
+	filter := Filter{
+		Countries: splitList(params.Get("country")),
+		Emotions:  splitList(params.Get("emotion")),
+	}
+
+	if value := params.Get("min_intensity"); value != "" {
+		parsed, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return Filter{}, fmt.Errorf("invalid min_intensity: %w", err)
+		}
+		filter.MinIntensity = parsed
+	}
+	if value := params.Get("bbox"); value != "" {
+		for _, part := range strings.Split(value, ",") {
+			parsed, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+			if err != nil {
+				return Filter{}, fmt.Errorf("invalid bbox: %w", err)
+			}
+			filter.BBox = append(filter.BBox, parsed)
+		}
+	}
+
+	return filter, filter.Validate()
+}
+
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if trimmed := strings.TrimSpace(item); trimmed != "" {
+			items = append(items, trimmed)
+		}
+	}
+	return items
+}
+
+// NewSSEClient creates a hub client for a Server-Sent Events stream. It has
+// no WebSocket connection; ServeSSE writes its messages to the response.
+func NewSSEClient(hub *Hub, filter Filter) *Client {
+	client := NewClient(hub, nil)
+	client.filter = filter
+	return client
+}
+
+// ParseSeq reads a sequence number passed as ?since= or Last-Event-ID
+func ParseSeq(value string) (uint64, error) {
+	seq, err := strconv.ParseUint(strings.TrimSpace(value), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid sequence number %q", value)
+	}
+	return seq, nil
+}
+
+// ServeSSE streams the client's messages as Server-Sent Events until the
+// request ends or the hub removes the client, which must already be registered.
+// Every message is a "data:" line with the same JSON as over the WebSocket and
+// its sequence number as the event ID, so a reconnecting EventSource resumes
+// where it left off.
+func (c *Client) ServeSSE(w http.ResponseWriter, r *http.Request) {
+	defer func() {
+		c.Hub.Unregister <- c
+	}()
+
+	controller := http.NewResponseController(w)
+	config := c.Hub.Config
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no") // don't let nginx buffer the stream
+	// browsers may connect from any origin, as /ws accepts every origin
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.WriteHeader(http.StatusOK)
+	if err := controller.Flush(); err != nil {
+		return
+	}
+
+	// comments keep proxies from timing out an idle stream
+	ticker := time.NewTicker(config.PingInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case message, ok := <-c.Send:
+			if !ok {
+				return
+			}
+			controller.SetWriteDeadline(time.Now().Add(config.WriteWait))
+			if err := writeSSE(w, message); err != nil {
+				log.Printf("SSE write error: %v", err)
+				return
+			}
+			if err := controller.Flush(); err != nil {
+				return
+			}
+			c.sent.Add(1)
+
+		case <-ticker.C:
+			controller.SetWriteDeadline(time.Now().Add(config.WriteWait))
+			if _, err := fmt.Fprint(w, ": keepalive\n\n"); err != nil {
+				return
+			}
+			if err := controller.Flush(); err != nil {
+				return
+			}
+
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+// writeSSE writes one event; JSON never contains raw newlines, so the payload
+// fits on a single data line
+func writeSSE(w http.ResponseWriter, message Message) error {
+	payload, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(w, "id: %d\ndata: %s\n\n", message.Seq, payload)
+	return err
+}
@@ -0,0 +1,241 @@
+package websocket
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// sseEvent is one event read from a stream
+type sseEvent struct {
+	id      uint64
+	message received
+}
+
+// newSSEServer serves hub's messages as Server-Sent Events, reading the
+// filter and resume point like main's /events handler
+func newSSEServer(t *testing.T, hub *Hub) *httptest.Server {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		filter, err := ParseFilter(r.URL.Query())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		client := NewSSEClient(hub, filter)
+		if since := r.Header.Get("Last-Event-ID"); since != "" {
+			seq, err := ParseSeq(since)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			client.Resume(seq)
+		}
+		hub.Register <- client
+		client.ServeSSE(w, r)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+// openStream connects to an SSE server and returns a reader of its events;
+// the stream is closed when the test ends or close is called
+func openStream(t *testing.T, server *httptest.Server, query, lastEventID string) (*http.Response, func() sseEvent, func()) {
+	t.Helper()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+"?"+query, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lastEventID != "" {
+		request.Header.Set("Last-Event-ID", lastEventID)
+	}
+	response, err := http.DefaultClient.Do(request)
+	if err != nil {
+		t.Fatalf("GET: %v", err)
+	}
+
+	events := make(chan sseEvent)
+	go func() {
+		defer close(events)
+		var event sseEvent
+		scanner := bufio.NewScanner(response.Body)
+		for scanner.Scan() {
+			line := scanner.Text()
+			switch {
+			case strings.HasPrefix(line, "id: "):
+				event.id, _ = strconv.ParseUint(strings.TrimPrefix(line, "id: "), 10, 64)
+			case strings.HasPrefix(line, "data: "):
+				json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &event.message)
+			case line == "" && event.message.Type != "":
+				events <- event
+				event = sseEvent{}
+			}
+		}
+	}()
+
+	next := func() sseEvent {
+		t.Helper()
+		select {
+		case event, ok := <-events:
+			if !ok {
+				t.Fatal("stream ended")
+			}
+			return event
+		case <-time.After(2 * time.Second):
+			t.Fatal("no event in time")
+		}
+		return sseEvent{}
+	}
+	closeStream := func() {
+		cancel()
+		response.Body.Close()
+	}
+	return response, next, closeStream
+}
+
+// country decodes the country of an emotion event
+func (e sseEvent) country(t *testing.T) string {
+	t.Helper()
+
+	var data EmotionData
+	if err := json.Unmarshal(e.message.Data, &data); err != nil {
+		t.Fatalf("emotion: %v", err)
+	}
+	return data.Country
+}
+
+func TestServeSSE(t *testing.T) {
+	hub := newTestHub(t, testConfig())
+	server := newSSEServer(t, hub)
+
+	response, next, closeStream := openStream(t, server, "country=bo", "")
+	waitFor(t, time.Second, func() bool { return hub.Stats().Clients == 1 })
+
+	for header, want := range map[string]string{
+		"Content-Type":                "text/event-stream",
+		"Cache-Control":               "no-cache",
+		"Access-Control-Allow-Origin": "*",
+	} {
+		if got := response.Header.Get(header); got != want {
+			t.Errorf("%s = %q, want %q", header, got, want)
+		}
+	}
+
+	emotion := func(country string) Message {
+		return Message{Type: MessageTypeEmotion, Data: EmotionData{Country: country, CountryCode: country[:2]}}
+	}
+	for _, country := range []string{"Peru", "bo", "Peru", "bo"} {
+		hub.Broadcast <- emotion(country)
+	}
+
+	// only matching events arrive, each with its sequence number as the ID
+	var last uint64
+	for i := 0; i < 2; i++ {
+		event := next()
+		if event.message.Type != MessageTypeEmotion || event.country(t) != "bo" {
+			t.Fatalf("event %d = %+v, want a bo emotion", i, event.message)
+		}
+		if event.id != event.message.Seq || event.id <= last {
+			t.Errorf("event %d has id %d and seq %d after %d", i, event.id, event.message.Seq, last)
+		}
+		last = event.id
+	}
+
+	closeStream()
+	waitFor(t, time.Second, func() bool { return hub.Stats().Clients == 0 })
+
+	// broadcasts while disconnected are replayed to a resuming stream
+	for _, country := range []string{"bo", "Peru", "bo"} {
+		hub.Broadcast <- emotion(country)
+	}
+	_, next, _ = openStream(t, server, "country=bo", strconv.FormatUint(last, 10))
+
+	var resumed []uint64
+	for i := 0; i < 2; i++ {
+		event := next()
+		if event.message.Type != MessageTypeEmotion || event.country(t) != "bo" {
+			t.Fatalf("resumed event %d = %+v, want a bo emotion", i, event.message)
+		}
+		resumed = append(resumed, event.id)
+	}
+	if want := []uint64{last + 1, last + 3}; !reflect.DeepEqual(resumed, want) {
+		t.Errorf("resumed ids %v, want %v", resumed, want)
+	}
+}
+
+func TestServeSSEResumeAfterRestart(t *testing.T) {
+	hub := newTestHub(t, testConfig())
+	server := newSSEServer(t, hub)
+
+	// an id from the future, as a server whose clock went backwards would see
+	_, next, _ := openStream(t, server, "", strconv.FormatUint(uint64(time.Now().Add(time.Hour).UnixMicro()), 10))
+	if event := next(); event.message.Type != MessageTypeReset {
+		t.Errorf("first event is a %s, want a reset", event.message.Type)
+	}
+}
+
+func TestParseFilter(t *testing.T) {
+	tests := []struct {
+		query   string
+		want    Filter
+		wantErr bool
+	}{
+		{"", Filter{}, false},
+		{"country=bo,+Peru+,&emotion=joy", Filter{Countries: []string{"bo", "Peru"}, Emotions: []string{"joy"}}, false},
+		{"min_intensity=0.5", Filter{MinIntensity: 0.5}, false},
+		{"bbox=-70,+-23,-57,-9", Filter{BBox: []float64{-70, -23, -57, -9}}, false},
+		{"min_intensity=high", Filter{}, true},
+		{"min_intensity=2", Filter{}, true},
+		{"bbox=-70,-23,-57", Filter{}, true},
+		{"bbox=a,b,c,d", Filter{}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			params, err := url.ParseQuery(tt.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			filter, err := ParseFilter(params)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseFilter() error = %v, want error %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(filter, tt.want) {
+				t.Errorf("ParseFilter() = %+v, want %+v", filter, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseSeq(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    uint64
+		wantErr bool
+	}{
+		{"42", 42, false},
+		{" 1735732800000042 ", 1735732800000042, false},
+		{"", 0, true},
+		{"-1", 0, true},
+		{"abc", 0, true},
+	}
+
+	for _, tt := range tests {
+		seq, err := ParseSeq(tt.value)
+		if (err != nil) != tt.wantErr || seq != tt.want {
+			t.Errorf("ParseSeq(%q) = %d, %v; want %d, error %v", tt.value, seq, err, tt.want, tt.wantErr)
+		}
+	}
+}